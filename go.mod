@@ -2,9 +2,11 @@ module github.com/ridewestside/website
 
 go 1.25.5
 
-require github.com/magefile/mage v1.15.0
-
 require (
-	golang.org/x/sys v0.41.0 // indirect
-	golang.org/x/term v0.40.0 // indirect
+	github.com/magefile/mage v1.15.0
+	golang.org/x/net v0.51.0
+	golang.org/x/term v0.40.0
+	gopkg.in/yaml.v3 v3.0.1
 )
+
+require golang.org/x/sys v0.41.0 // indirect
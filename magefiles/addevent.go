@@ -4,11 +4,7 @@ package main
 
 import (
 	"bufio"
-	"bytes"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"regexp"
 	"strconv"
@@ -19,70 +15,99 @@ import (
 )
 
 // AddEvent creates a new event, optionally posts it to Shift2Bikes, and appends it to events.md.
+// Given several dates it adds one events.md entry per date and, with create,
+// a single Shift2Bikes event covering all of them.
 //
 // All prompts can be pre-filled via environment variables for non-interactive use.
 // In a non-interactive terminal, any missing required variable causes exit 1.
 //
 // Environment variables:
 //
-//	EVENT_TYPE         beaverton, tigard, or custom
-//	EVENT_DATE         MM/DD or MM/DD/YYYY
+//	EVENT_TYPE         a type name from data/event-templates.yaml (beaverton, tigard, custom, ...)
+//	EVENT_DATE         MM/DD, MM/DD/YYYY, YYYY-MM-DD, "next monday" or "2nd monday of march";
+//	                   comma-separated for a series. Dates without a year are the next one to come
 //	EVENT_TITLE        event title (custom only)
 //	EVENT_DETAILS      event description (custom only)
-//	EVENT_TIME         start time HH:MM:SS (custom only)
-//	EVENT_TIME_DETAILS display time e.g. "10am to 2pm" (custom only)
+//	EVENT_TIME         start time, e.g. 6:30pm or 18:30 (custom only)
+//	EVENT_DURATION     length, e.g. 2h or 90m (custom only, optional)
+//	EVENT_TIME_DETAILS display time (custom only; default from time and duration, e.g. "10am to 2pm")
 //	EVENT_VENUE        venue name (custom only)
 //	EVENT_ADDRESS      address (custom only)
 //	EVENT_AREA         area code N/NE/NW/SE/SW/E/W (custom only)
 //	EVENT_LOC_DETAILS  location details (custom only)
+//	EVENT_START_ADDRESS meeting address, required for types whose venue has none
 //	EVENT_START        start location for events.md
 //	EVENT_END          end location for events.md
 //	EVENT_SHIFT_MODE   create, existing, or skip
-//	EVENT_SHIFT_URL    existing Shift2Bikes calendar URL
+//	EVENT_SHIFT_URL    existing Shift2Bikes calendar URL, one per date for a series
 //	EVENT_ROUTE        RideWithGPS route URL (optional)
+//	EVENT_TAGS         comma-separated tags (default happy-hour for happy hours, ride for custom)
 //	EVENT_SECTION      YAML comment text to insert after (optional)
+//	EVENT_POST_RIDE    yes to also add the type's post ride (the Post-Bike Happy Hour Ride for beaverton)
+//	EVENT_POST_SHIFT_MODE  create, existing, or skip for the post ride
+//	EVENT_POST_SHIFT_URL   existing Shift2Bikes calendar URL for the post ride, one per date
+//	EVENT_POST_ROUTE       RideWithGPS route URL for the post ride (optional)
 //	EVENT_CONFIRM      yes to skip confirmation prompt
 func AddEvent() error {
+	tmpl, err := loadEventTemplates(eventTemplatesFile)
+	if err != nil {
+		return err
+	}
+
 	// Choose event type
-	eventTypes := []string{"Beaverton Happy Hour", "Tigard Happy Hour", "Custom Event"}
-	typeIdx, err := resolveChoice("EVENT_TYPE", "Select event type", eventTypes)
+	typ, err := resolveEventType(tmpl)
 	if err != nil {
 		return err
 	}
 
-	// Collect event details based on type
-	var entry eventEntry
+	// Collect event details based on type, one entry per date
+	var entries []eventEntry
 	var payload *shift2bikesPayload
-	switch typeIdx {
-	case 0:
-		entry, payload, err = collectBeavertonHappyHour()
-	case 1:
-		entry, payload, err = collectTigardHappyHour()
-	case 2:
-		entry, payload, err = collectCustomEvent()
+	if typ.Custom {
+		entries, payload, err = collectCustomEvent(typ)
+	} else {
+		entries, payload, err = collectTemplateEvent(typ)
 	}
 	if err != nil {
 		return err
 	}
 
 	// Handle Shift2Bikes integration
-	shiftURL, err := handleShift2Bikes(payload, typeIdx)
-	if err != nil {
+	if err := handleShift2Bikes(payload, typ, entries); err != nil {
 		return err
 	}
-	if shiftURL != "" {
-		entry.URL = shiftURL
-	}
 
 	// Optional route URL
 	route, err := resolveOptional("EVENT_ROUTE", "RideWithGPS route URL (optional, press enter to skip)")
 	if err != nil {
 		return err
 	}
-	entry.Route = route
+
+	// Tags drive the filter chips on the site
+	tags, err := resolveTags("EVENT_TAGS", entries[0].Tags)
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		entries[i].Route = route
+		entries[i].Tags = tags
+	}
+
+	// Types with a post ride (Beaverton happy hours) can bring it along
+	var postRides []eventEntry
+	if typ.postRide != nil {
+		if postRides, err = collectPostRide(entries, typ.postRide); err != nil {
+			return err
+		}
+	}
 
 	// Summary and confirmation
-	printEventSummary(entry)
+	for i, entry := range entries {
+		printEventSummary(entry)
+		if postRides != nil {
+			printEventSummary(postRides[i])
+		}
+	}
 
 	confirmed, err := resolveConfirm("EVENT_CONFIRM", "Add this event to events.md?")
 	if err != nil {
@@ -94,76 +119,122 @@ func AddEvent() error {
 	}
 
 	// Determine which section to insert into
-	section, err := determineSection(typeIdx)
+	section, err := determineSection(typ)
 	if err != nil {
 		return err
 	}
 
-	// Append to events.md
-	eventsPath := "content/events.md"
-	if err := appendEventToFile(eventsPath, entry, section); err != nil {
-		return fmt.Errorf("failed to update %s: %w", eventsPath, err)
+	// Add to events.md
+	doc, err := loadEventsDoc(eventsFile)
+	if err != nil {
+		return err
+	}
+	for i, entry := range entries {
+		ev, err := doc.Insert(entry, section)
+		if err != nil {
+			return err
+		}
+		if postRides != nil {
+			doc.InsertAfter(ev, postRides[i])
+		}
+	}
+	if err := doc.Save(); err != nil {
+		return fmt.Errorf("failed to update %s: %w", eventsFile, err)
+	}
+	if len(entries) == 1 {
+		fmt.Printf("\nEvent added to %s\n", eventsFile)
+	} else {
+		fmt.Printf("\n%d events added to %s\n", len(entries), eventsFile)
 	}
-	fmt.Printf("\nEvent added to %s\n", eventsPath)
 
-	// Social media templates
-	printSocialTemplates(entry)
+	// Social media templates, for the first date of a series
+	printSocialTemplates(entries[0])
 
 	return nil
 }
 
+// resolveEventType picks a type from data/event-templates.yaml. EVENT_TYPE
+// may be a type name or a menu label.
+func resolveEventType(tmpl *eventTemplates) (*eventType, error) {
+	if v := os.Getenv("EVENT_TYPE"); v != "" {
+		for _, t := range tmpl.Types {
+			if strings.EqualFold(t.Name, v) {
+				return t, nil
+			}
+		}
+	}
+	menu := tmpl.Menu()
+	labels := make([]string, len(menu))
+	for i, t := range menu {
+		labels[i] = t.Label
+	}
+	idx, err := resolveChoice("EVENT_TYPE", "Select event type", labels)
+	if err != nil {
+		return nil, err
+	}
+	return menu[idx], nil
+}
+
+// collectPostRide asks whether to add the post ride for each event (the
+// Post-Bike Happy Hour Ride for a Beaverton happy hour) and, if so, resolves
+// its own Shift2Bikes link and route. The rides share one Shift2Bikes event.
+func collectPostRide(parents []eventEntry, typ *eventType) ([]eventEntry, error) {
+	add, err := resolveOptionalConfirm("EVENT_POST_RIDE", fmt.Sprintf("Also add the %s?", typ.Label))
+	if err != nil || !add {
+		return nil, err
+	}
+
+	rides := make([]eventEntry, len(parents))
+	dates := make([]time.Time, len(parents))
+	for i, parent := range parents {
+		t, err := time.ParseInLocation(eventDateLayout, parent.Date, time.Local)
+		if err != nil {
+			return nil, err
+		}
+		if rides[i], err = typ.entry(t); err != nil {
+			return nil, err
+		}
+		dates[i] = t
+	}
+	payload, err := typ.payload(dates...)
+	if err != nil {
+		return nil, err
+	}
+
+	if isInteractive() {
+		fmt.Printf("\n%s:\n", typ.Label)
+	}
+	mode, err := resolveShiftMode("EVENT_POST_SHIFT_MODE", typ.ShiftMode)
+	if err != nil {
+		return nil, err
+	}
+	if err := handleShiftMode(mode, payload, "EVENT_POST_SHIFT_URL", rides); err != nil {
+		return nil, err
+	}
+
+	route, err := resolveOptional("EVENT_POST_ROUTE", "Post-ride RideWithGPS route URL (optional, press enter to skip)")
+	if err != nil {
+		return nil, err
+	}
+	for i := range rides {
+		rides[i].Route = route
+	}
+	return rides, nil
+}
+
 // --- Data structures ---
 
 type eventEntry struct {
-	Title        string
-	Date         string // display format: "January 2, 2026"
-	URL          string
-	Route        string
-	Start        string
-	End          string
-	StartAddress string // specific street address for map navigation
-}
-
-type shift2bikesPayload struct {
-	ID            string       `json:"id"`
-	Secret        string       `json:"secret"`
-	Title         string       `json:"title"`
-	Details       string       `json:"details"`
-	Audience      string       `json:"audience"`
-	Time          string       `json:"time"`
-	TimeDetails   string       `json:"timedetails"`
-	EventDuration string       `json:"eventduration"`
-	Area          string       `json:"area"`
-	Venue         string       `json:"venue"`
-	Address       string       `json:"address"`
-	LocDetails    string       `json:"locdetails"`
-	LocEnd        string       `json:"locend"`
-	Length        string       `json:"length"`
-	Organizer     string       `json:"organizer"`
-	Email         string       `json:"email"`
-	HideEmail     string       `json:"hideemail"`
-	WebName       string       `json:"webname"`
-	WebURL        string       `json:"weburl"`
-	Phone         string       `json:"phone"`
-	Contact       string       `json:"contact"`
-	TinyTitle     string       `json:"tinytitle"`
-	PrintDescr    string       `json:"printdescr"`
-	CodeOfConduct string       `json:"code_of_conduct"`
-	ReadComic     string       `json:"read_comic"`
-	DateStatuses  []dateStatus `json:"datestatuses"`
-}
-
-type dateStatus struct {
-	ID        string `json:"id"`
-	Date      string `json:"date"`
-	Status    string `json:"status"`
-	Newsflash string `json:"newsflash"`
-}
-
-type shift2bikesResponse struct {
-	DateStatuses []struct {
-		ID string `json:"id"`
-	} `json:"datestatuses"`
+	Title        string   `yaml:"title"`
+	Date         string   `yaml:"date"` // display format: "January 2, 2026"
+	URL          string   `yaml:"url"`
+	Route        string   `yaml:"route"`
+	Start        string   `yaml:"start"`
+	End          string   `yaml:"end"`
+	StartAddress string   `yaml:"start_address"` // specific street address for map navigation
+	Tags         []string `yaml:"tags"`
+	Status       string   `yaml:"status"` // "cancelled", or empty for a normal event
+	Reason       string   `yaml:"reason"` // shown with cancelled events
 }
 
 // --- Interactive / non-interactive helpers ---
@@ -204,6 +275,15 @@ func resolveValue(envVar, prompt, defaultVal string) (string, error) {
 	return val, nil
 }
 
+// resolveValueDefault is like resolveValue but falls back to defaultVal when
+// the variable is unset in a non-interactive terminal.
+func resolveValueDefault(envVar, prompt, defaultVal string) (string, error) {
+	if os.Getenv(envVar) == "" && !isInteractive() && defaultVal != "" {
+		return defaultVal, nil
+	}
+	return resolveValue(envVar, prompt, defaultVal)
+}
+
 func resolveOptional(envVar, prompt string) (string, error) {
 	if v := os.Getenv(envVar); v != "" {
 		return v, nil
@@ -264,229 +344,628 @@ func resolveConfirm(envVar, prompt string) (bool, error) {
 	return input == "y" || input == "yes", nil
 }
 
+// resolveConfirmDefault is like resolveConfirm but falls back to def when the
+// variable is unset in a non-interactive terminal or nothing is entered.
+func resolveConfirmDefault(envVar, prompt string, def bool) (bool, error) {
+	if os.Getenv(envVar) != "" {
+		return resolveConfirm(envVar, prompt)
+	}
+	if !isInteractive() {
+		return def, nil
+	}
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	fmt.Printf("%s (%s): ", prompt, hint)
+	scanner().Scan()
+	switch strings.ToLower(strings.TrimSpace(scanner().Text())) {
+	case "":
+		return def, nil
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// resolveOptionalConfirm is like resolveConfirm but answers no instead of
+// failing when the variable is unset in a non-interactive terminal.
+func resolveOptionalConfirm(envVar, prompt string) (bool, error) {
+	if os.Getenv(envVar) == "" && !isInteractive() {
+		return false, nil
+	}
+	return resolveConfirm(envVar, prompt)
+}
+
+// resolveEvent finds one event in doc from a title, date or URL. When several
+// events match, the user picks one; non-interactively that is an error.
+func resolveEvent(doc *eventsDoc, envVar, prompt string) (*docEvent, error) {
+	sel, err := resolveValue(envVar, prompt, "")
+	if err != nil {
+		return nil, err
+	}
+	matches := doc.MatchEvents(sel)
+	switch {
+	case len(matches) == 0:
+		return nil, fmt.Errorf("no event in %s matches %q", doc.path, sel)
+	case len(matches) == 1:
+		return matches[0], nil
+	}
+
+	options := make([]string, len(matches))
+	for i, ev := range matches {
+		options[i] = fmt.Sprintf("%s (%s, line %d)", ev.Title, ev.Date, ev.Line)
+	}
+	if !isInteractive() {
+		return nil, fmt.Errorf("%q matches %d events; be more specific:\n  %s", sel, len(matches), strings.Join(options, "\n  "))
+	}
+	idx, err := resolveChoice("", fmt.Sprintf("%q matches several events", sel), options)
+	if err != nil {
+		return nil, err
+	}
+	return matches[idx], nil
+}
+
+// resolveTags reads a comma- or space-separated tag list, falling back to
+// defaults when nothing is entered. Only tags in knownEventTags are accepted.
+func resolveTags(envVar string, defaults []string) ([]string, error) {
+	if v := os.Getenv(envVar); v != "" {
+		return parseTags(v)
+	}
+	if !isInteractive() {
+		return defaults, nil
+	}
+	for {
+		fmt.Printf("Tags (known: %s) [%s]: ", strings.Join(knownEventTags, ", "), strings.Join(defaults, ", "))
+		scanner().Scan()
+		input := strings.TrimSpace(scanner().Text())
+		if input == "" {
+			return defaults, nil
+		}
+		tags, err := parseTags(input)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		return tags, nil
+	}
+}
+
+func parseTags(raw string) ([]string, error) {
+	known := make(map[string]bool)
+	for _, t := range knownEventTags {
+		known[t] = true
+	}
+	var tags []string
+	seen := make(map[string]bool)
+	for _, t := range strings.FieldsFunc(strings.ToLower(raw), func(r rune) bool { return r == ',' || r == ' ' }) {
+		if !known[t] {
+			return nil, fmt.Errorf("unknown tag %q; known tags: %s", t, strings.Join(knownEventTags, ", "))
+		}
+		if !seen[t] {
+			seen[t] = true
+			tags = append(tags, t)
+		}
+	}
+	return tags, nil
+}
+
 type parsedDate struct {
+	t       time.Time
 	api     string // YYYY-MM-DD
 	display string // January 2, 2026
 	short   string // 1/2
 }
 
-var dateRegexFull = regexp.MustCompile(`^\d{1,2}/\d{1,2}/\d{4}$`)
-var dateRegexShort = regexp.MustCompile(`^\d{1,2}/\d{1,2}$`)
+var (
+	dateRegexFull    = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})/(\d{4})$`)
+	dateRegexShort   = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})$`)
+	dateRegexISO     = regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})$`)
+	dateRegexOrdinal = regexp.MustCompile(`^(1st|2nd|3rd|4th|5th|first|second|third|fourth|fifth|last) ([a-z]+) (?:of|in) ([a-z]+)(?: (\d{4}))?$`)
+	dateRegexMonth   = regexp.MustCompile(`^([a-z]+) (\d{1,2})(?:st|nd|rd|th)?(?: (\d{4}))?$`)
+	dateRegexWeekday = regexp.MustCompile(`^(?:(next|this) )?([a-z]+)$`)
+	dateRegexYear    = regexp.MustCompile(`^\d{4}$`)
+)
 
-func resolveDate(envVar string) (parsedDate, error) {
+var dateOrdinals = map[string]int{
+	"1st": 1, "first": 1, "2nd": 2, "second": 2, "3rd": 3, "third": 3,
+	"4th": 4, "fourth": 4, "5th": 5, "fifth": 5, "last": -1,
+}
+
+const dateFormatsHelp = `use MM/DD, MM/DD/YYYY, YYYY-MM-DD, "March 9", "next monday" or "2nd monday of march"`
+
+// resolveDates reads a comma-separated list of dates, e.g. the dates of a
+// series. Duplicates are rejected. Dates in the past or on the wrong weekday
+// for typ are reported; interactively they must be confirmed.
+func resolveDates(envVar string, typ *eventType) ([]parsedDate, error) {
 	raw := os.Getenv(envVar)
 	if raw == "" {
 		if !isInteractive() {
-			return parsedDate{}, fmt.Errorf("non-interactive: set %s environment variable", envVar)
+			return nil, fmt.Errorf("non-interactive: set %s environment variable", envVar)
 		}
 		for {
-			fmt.Print("Enter event date (MM/DD/YYYY or MM/DD): ")
+			fmt.Print("Enter event date(s) (e.g. 1/12, 2027-01-12 or 2nd monday of march; comma-separated for a series): ")
 			scanner().Scan()
-			raw = strings.TrimSpace(scanner().Text())
-			d, err := parseDate(raw)
+			dates, err := parseDates(scanner().Text(), time.Now())
 			if err != nil {
 				fmt.Println(err)
 				continue
 			}
-			return d, nil
+			if warnings := typ.dateWarnings(dates); len(warnings) > 0 {
+				for _, w := range warnings {
+					fmt.Printf("WARNING: %s\n", w)
+				}
+				ok, err := resolveConfirmDefault("", "Use these dates anyway?", false)
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					continue
+				}
+			}
+			return dates, nil
 		}
 	}
-	return parseDate(raw)
+	dates, err := parseDates(raw, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", envVar, err)
+	}
+	for _, w := range typ.dateWarnings(dates) {
+		fmt.Printf("WARNING: %s\n", w)
+	}
+	return dates, nil
 }
 
-func parseDate(raw string) (parsedDate, error) {
-	if dateRegexShort.MatchString(raw) {
-		parts := strings.Split(raw, "/")
-		month, _ := strconv.Atoi(parts[0])
-		day, _ := strconv.Atoi(parts[1])
-		year := time.Now().Year()
-		t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.Local)
-		if t.Month() != time.Month(month) || t.Day() != day {
-			return parsedDate{}, fmt.Errorf("invalid date: %s", raw)
-		}
-		return parsedDate{
-			api:     fmt.Sprintf("%d-%02d-%02d", year, month, day),
-			display: t.Format("January 2, 2006"),
-			short:   fmt.Sprintf("%d/%d", month, day),
-		}, nil
-	}
-	if dateRegexFull.MatchString(raw) {
-		parts := strings.Split(raw, "/")
-		month, _ := strconv.Atoi(parts[0])
-		day, _ := strconv.Atoi(parts[1])
-		year, _ := strconv.Atoi(parts[2])
+// parseDates reads a comma-separated list of dates relative to now.
+func parseDates(raw string, now time.Time) ([]parsedDate, error) {
+	// "January 12, 2027" splits into two parts; glue the year back on
+	var parts []string
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if dateRegexYear.MatchString(part) && len(parts) > 0 {
+			parts[len(parts)-1] += " " + part
+			continue
+		}
+		parts = append(parts, part)
+	}
+
+	var dates []parsedDate
+	seen := make(map[string]bool)
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		d, err := parseDate(part, now)
+		if err != nil {
+			return nil, err
+		}
+		if seen[d.api] {
+			return nil, fmt.Errorf("date %s given twice", d.display)
+		}
+		seen[d.api] = true
+		dates = append(dates, d)
+	}
+	if len(dates) == 0 {
+		return nil, fmt.Errorf("no date given")
+	}
+	return dates, nil
+}
+
+func newParsedDate(t time.Time) parsedDate {
+	return parsedDate{
+		t:       t,
+		api:     t.Format("2006-01-02"),
+		display: t.Format(eventDateLayout),
+		short:   fmt.Sprintf("%d/%d", int(t.Month()), t.Day()),
+	}
+}
+
+// parseDate reads a date in any of the forms in dateFormatsHelp. Dates
+// without a year are the next occurrence on or after now's day.
+func parseDate(raw string, now time.Time) (parsedDate, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	norm := strings.Join(strings.Fields(strings.ToLower(raw)), " ")
+	norm = strings.NewReplacer(",", "", ".", "").Replace(norm)
+
+	if m := dateRegexFull.FindStringSubmatch(norm); m != nil {
+		return exactDate(raw, atoi(m[3]), atoi(m[1]), atoi(m[2]))
+	}
+	if m := dateRegexISO.FindStringSubmatch(norm); m != nil {
+		return exactDate(raw, atoi(m[1]), atoi(m[2]), atoi(m[3]))
+	}
+	if m := dateRegexShort.FindStringSubmatch(norm); m != nil {
+		return nextDate(raw, today, atoi(m[1]), atoi(m[2]))
+	}
+	switch norm {
+	case "today":
+		return newParsedDate(today), nil
+	case "tomorrow":
+		return newParsedDate(today.AddDate(0, 0, 1)), nil
+	}
+	if m := dateRegexOrdinal.FindStringSubmatch(norm); m != nil {
+		wd, err := parseWeekday(m[2])
+		if err != nil {
+			return parsedDate{}, fmt.Errorf("invalid date %q: %w", raw, err)
+		}
+		month, ok := parseMonth(m[3])
+		if !ok {
+			return parsedDate{}, fmt.Errorf("invalid date %q: unknown month %q", raw, m[3])
+		}
+		n := dateOrdinals[m[1]]
+		pick := func(year int) time.Time {
+			if n == -1 {
+				return lastWeekday(year, month, wd)
+			}
+			return nthWeekday(year, month, wd, n)
+		}
+		if m[4] != "" {
+			t := pick(atoi(m[4]))
+			if t.IsZero() {
+				return parsedDate{}, fmt.Errorf("invalid date %q: %s %s has no %s %s", raw, month, m[4], m[1], wd)
+			}
+			return newParsedDate(t), nil
+		}
+		for year := today.Year(); year < today.Year()+8; year++ {
+			if t := pick(year); !t.IsZero() && !t.Before(today) {
+				return newParsedDate(t), nil
+			}
+		}
+		return parsedDate{}, fmt.Errorf("invalid date %q: no %s %s of %s coming up", raw, m[1], wd, month)
+	}
+	if m := dateRegexMonth.FindStringSubmatch(norm); m != nil {
+		if month, ok := parseMonth(m[1]); ok {
+			if m[3] != "" {
+				return exactDate(raw, atoi(m[3]), int(month), atoi(m[2]))
+			}
+			return nextDate(raw, today, int(month), atoi(m[2]))
+		}
+	}
+	if m := dateRegexWeekday.FindStringSubmatch(norm); m != nil {
+		if wd, err := parseWeekday(m[2]); err == nil {
+			days := (int(wd) - int(today.Weekday()) + 7) % 7
+			if days == 0 && m[1] == "next" {
+				days = 7
+			}
+			return newParsedDate(today.AddDate(0, 0, days)), nil
+		}
+	}
+	return parsedDate{}, fmt.Errorf("invalid date %q; %s", raw, dateFormatsHelp)
+}
+
+// exactDate checks that year/month/day is a real date.
+func exactDate(raw string, year, month, day int) (parsedDate, error) {
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.Local)
+	if t.Year() != year || t.Month() != time.Month(month) || t.Day() != day {
+		return parsedDate{}, fmt.Errorf("invalid date: %s", raw)
+	}
+	return newParsedDate(t), nil
+}
+
+// nextDate returns the first month/day on or after today, so "1/12" entered
+// in December is next January.
+func nextDate(raw string, today time.Time, month, day int) (parsedDate, error) {
+	for year := today.Year(); year < today.Year()+8; year++ {
 		t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.Local)
-		if t.Month() != time.Month(month) || t.Day() != day {
-			return parsedDate{}, fmt.Errorf("invalid date: %s", raw)
+		if t.Month() == time.Month(month) && t.Day() == day && !t.Before(today) {
+			return newParsedDate(t), nil
 		}
-		return parsedDate{
-			api:     fmt.Sprintf("%d-%02d-%02d", year, month, day),
-			display: t.Format("January 2, 2006"),
-			short:   fmt.Sprintf("%d/%d", month, day),
-		}, nil
 	}
-	return parsedDate{}, fmt.Errorf("invalid date format %q; use MM/DD/YYYY or MM/DD", raw)
+	return parsedDate{}, fmt.Errorf("invalid date: %s", raw)
 }
 
-// --- Event collectors ---
+func parseMonth(name string) (time.Month, bool) {
+	for m := time.January; m <= time.December; m++ {
+		if strings.EqualFold(name, m.String()) || strings.EqualFold(name, m.String()[:3]) {
+			return m, true
+		}
+	}
+	return 0, false
+}
 
-func collectBeavertonHappyHour() (eventEntry, *shift2bikesPayload, error) {
-	d, err := resolveDate("EVENT_DATE")
-	if err != nil {
-		return eventEntry{}, nil, err
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// resolveParsed is resolveValue for a typed value: parse validates the input
+// and returns its normalized form. Interactive input is re-prompted until it
+// parses; a bad environment variable is an error.
+func resolveParsed(envVar, prompt, defaultVal string, parse func(string) (string, error)) (string, error) {
+	if v := os.Getenv(envVar); v != "" {
+		val, err := parse(v)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", envVar, err)
+		}
+		return val, nil
+	}
+	if !isInteractive() {
+		return "", fmt.Errorf("non-interactive: set %s environment variable", envVar)
 	}
+	for {
+		if defaultVal != "" {
+			fmt.Printf("%s [%s]: ", prompt, defaultVal)
+		} else {
+			fmt.Printf("%s: ", prompt)
+		}
+		scanner().Scan()
+		input := strings.TrimSpace(scanner().Text())
+		if input == "" {
+			input = defaultVal
+		}
+		if input == "" {
+			fmt.Printf("%s is required\n", prompt)
+			continue
+		}
+		val, err := parse(input)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		return val, nil
+	}
+}
 
-	entry := eventEntry{
-		Title:        fmt.Sprintf("%s Bike Happy Hour", d.short),
-		Date:         d.display,
-		Start:        "Beaverton",
-		End:          "Beaverton",
-		StartAddress: "4250 SW Rose Biggi Ave, Beaverton, OR",
+// resolveParsedOptional is resolveParsed for a value that may be left empty,
+// which is also the answer when the variable is unset non-interactively.
+func resolveParsedOptional(envVar, prompt string, parse func(string) (string, error)) (string, error) {
+	if os.Getenv(envVar) != "" {
+		return resolveParsed(envVar, prompt, "", parse)
+	}
+	if !isInteractive() {
+		return "", nil
 	}
+	for {
+		fmt.Printf("%s: ", prompt)
+		scanner().Scan()
+		input := strings.TrimSpace(scanner().Text())
+		if input == "" {
+			return "", nil
+		}
+		val, err := parse(input)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		return val, nil
+	}
+}
 
-	payload := &shift2bikesPayload{
-		Title:         fmt.Sprintf("Westside Bike Happy Hour %s", d.short),
-		Details:       "Join us on the westside for Bike Happy Hour. Meet new friends and old, hang out, grab a beverage (alcoholic or not), grab some food, and let's talk bikes! \r\n\r\nEveryone welcome!\r\n\r\nEvery 2nd and 4th Monday, 4:30 to 7 p.m.",
-		Audience:      "G",
-		Time:          "16:30:00",
-		TimeDetails:   "4:30 to 7pm",
-		Area:          "W",
-		Venue:         "BGs Food Cartel",
-		Address:       "4250 SW Rose Biggi Ave Beaverton, OR",
-		LocDetails:    "Meet in the back by the bar or in the indoor seating",
-		Length:        "--",
-		Organizer:     "Ride Westside",
-		Email:         "ridewestside2023@gmail.com",
-		HideEmail:     "1",
-		WebName:       "Ride Westside",
-		WebURL:        "https://ridewestside.org",
-		CodeOfConduct: "1",
-		ReadComic:     "1",
-		DateStatuses: []dateStatus{
-			{Date: d.api, Status: "A"},
-		},
+var eventTimeRegex = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(?::(\d{2}))?(am|pm|a|p)?$`)
+
+// parseEventTime accepts 24h times (18:30, 18:30:00) and 12h times (6:30pm,
+// 6 PM, 6:30 p.m.) and returns the HH:MM:SS the Shift2Bikes API expects.
+func parseEventTime(raw string) (string, error) {
+	norm := strings.NewReplacer(" ", "", ".", "").Replace(strings.ToLower(raw))
+	m := eventTimeRegex.FindStringSubmatch(norm)
+	if m == nil {
+		return "", fmt.Errorf("invalid time %q; use e.g. 6:30pm or 18:30", raw)
+	}
+	hour, _ := strconv.Atoi(m[1])
+	minute, sec := 0, 0
+	if m[2] != "" {
+		minute, _ = strconv.Atoi(m[2])
+	}
+	if m[3] != "" {
+		sec, _ = strconv.Atoi(m[3])
 	}
+	switch m[4] {
+	case "":
+		if m[2] == "" {
+			return "", fmt.Errorf("ambiguous time %q; add am/pm or use 24h HH:MM", raw)
+		}
+		if hour > 23 {
+			return "", fmt.Errorf("invalid time %q; hour must be 0-23", raw)
+		}
+	default:
+		if hour < 1 || hour > 12 {
+			return "", fmt.Errorf("invalid time %q; hour must be 1-12 with am/pm", raw)
+		}
+		hour %= 12
+		if m[4][0] == 'p' {
+			hour += 12
+		}
+	}
+	if minute > 59 || sec > 59 {
+		return "", fmt.Errorf("invalid time %q", raw)
+	}
+	return fmt.Sprintf("%02d:%02d:%02d", hour, minute, sec), nil
+}
 
-	return entry, payload, nil
+// parseArea returns the Shift2Bikes area code for raw, in any case.
+func parseArea(raw string) (string, error) {
+	area := strings.ToUpper(strings.TrimSpace(raw))
+	for _, a := range shift2bikesAreas {
+		if a == area {
+			return area, nil
+		}
+	}
+	return "", fmt.Errorf("invalid area %q; use one of %s", raw, strings.Join(shift2bikesAreas, ", "))
 }
 
-func collectTigardHappyHour() (eventEntry, *shift2bikesPayload, error) {
-	d, err := resolveDate("EVENT_DATE")
+// parseEventDuration accepts a Go duration (2h, 90m, 1h30m) or a number of
+// minutes and returns the minutes, as Shift2Bikes' eventduration wants.
+func parseEventDuration(raw string) (string, error) {
+	raw = strings.TrimSpace(strings.ToLower(raw))
+	d, err := time.ParseDuration(raw)
 	if err != nil {
-		return eventEntry{}, nil, err
+		n, nerr := strconv.Atoi(raw)
+		if nerr != nil {
+			return "", fmt.Errorf("invalid duration %q; use e.g. 2h, 90m or 1h30m", raw)
+		}
+		d = time.Duration(n) * time.Minute
 	}
+	if d < time.Minute || d >= 24*time.Hour || d%time.Minute != 0 {
+		return "", fmt.Errorf("invalid duration %q; use whole minutes under 24h", raw)
+	}
+	return strconv.Itoa(int(d / time.Minute)), nil
+}
 
-	entry := eventEntry{
-		Title: fmt.Sprintf("%s Tigard Happy Hour", d.short),
-		Date:  d.display,
-		Start: "Tigard",
-		End:   "Tigard",
+// formatTimeDetails describes a start time (HH:MM:SS) and optional duration
+// in minutes the way the happy hours do: "4:30 to 7pm", "10am to 2pm", or
+// just "6:30pm" without a duration.
+func formatTimeDetails(start, minutes string) string {
+	t, err := time.Parse("15:04:05", start)
+	if err != nil {
+		return ""
+	}
+	clock := func(t time.Time, suffix bool) string {
+		s := t.Format("3:04")
+		if t.Minute() == 0 {
+			s = t.Format("3")
+		}
+		if suffix {
+			s += strings.ToLower(t.Format("PM"))
+		}
+		return s
+	}
+	n, err := strconv.Atoi(minutes)
+	if err != nil || n <= 0 {
+		return clock(t, true)
 	}
+	end := t.Add(time.Duration(n) * time.Minute)
+	sameHalf := end.Day() == t.Day() && (t.Hour() < 12) == (end.Hour() < 12)
+	return fmt.Sprintf("%s to %s", clock(t, !sameHalf), clock(end, true))
+}
+
+// --- Event collectors ---
 
-	// Tigard events don't use Shift2Bikes by default
-	return entry, nil, nil
+// collectTemplateEvent builds an event of a non-custom type for each date in
+// EVENT_DATE, and their shared Shift2Bikes payload.
+func collectTemplateEvent(typ *eventType) ([]eventEntry, *shift2bikesPayload, error) {
+	dates, err := resolveDates("EVENT_DATE", typ)
+	if err != nil {
+		return nil, nil, err
+	}
+	entries := make([]eventEntry, len(dates))
+	times := make([]time.Time, len(dates))
+	for i, d := range dates {
+		if entries[i], err = typ.entry(d.t); err != nil {
+			return nil, nil, err
+		}
+		times[i] = d.t
+	}
+	payload, err := typ.payload(times...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if typ.needsAddress() {
+		address, err := resolveValue("EVENT_START_ADDRESS", fmt.Sprintf("Meeting address (%s has none on file)", typ.venue.Name), "")
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := range entries {
+			entries[i].StartAddress = address
+		}
+		if payload != nil {
+			payload.Address = address
+		}
+	}
+	return entries, payload, nil
 }
 
-func collectCustomEvent() (eventEntry, *shift2bikesPayload, error) {
-	d, err := resolveDate("EVENT_DATE")
+// collectCustomEvent prompts for a one-off event, defaulting the venue
+// fields to the type's venue.
+func collectCustomEvent(typ *eventType) ([]eventEntry, *shift2bikesPayload, error) {
+	dates, err := resolveDates("EVENT_DATE", typ)
 	if err != nil {
-		return eventEntry{}, nil, err
+		return nil, nil, err
 	}
 
 	title, err := resolveValue("EVENT_TITLE", "Event title", "")
 	if err != nil {
-		return eventEntry{}, nil, err
+		return nil, nil, err
 	}
 	details, err := resolveValue("EVENT_DETAILS", "Event description", "")
 	if err != nil {
-		return eventEntry{}, nil, err
+		return nil, nil, err
 	}
-	eventTime, err := resolveValue("EVENT_TIME", "Start time (HH:MM:SS, 24h)", "10:00:00")
+	eventTime, err := resolveParsed("EVENT_TIME", "Start time (e.g. 6:30pm or 18:30)", "10:00:00", parseEventTime)
 	if err != nil {
-		return eventEntry{}, nil, err
+		return nil, nil, err
 	}
-	timeDetails, err := resolveValue("EVENT_TIME_DETAILS", "Time description (e.g. '10am to 2pm')", "")
+	duration, err := resolveParsedOptional("EVENT_DURATION", "Duration (e.g. 2h or 90m, optional)", parseEventDuration)
 	if err != nil {
-		return eventEntry{}, nil, err
+		return nil, nil, err
 	}
-	venue, err := resolveValue("EVENT_VENUE", "Venue name", "Beaverton Central MAX Station")
+	timeDetails, err := resolveValueDefault("EVENT_TIME_DETAILS", "Time description", formatTimeDetails(eventTime, duration))
 	if err != nil {
-		return eventEntry{}, nil, err
+		return nil, nil, err
 	}
-	address, err := resolveValue("EVENT_ADDRESS", "Address", "12700 SW Crescent St, Beaverton, OR 97005")
+	venue, err := resolveValue("EVENT_VENUE", "Venue name", typ.venue.Name)
 	if err != nil {
-		return eventEntry{}, nil, err
+		return nil, nil, err
 	}
-	area, err := resolveValue("EVENT_AREA", "Area code (N, NE, NW, SE, SW, E, W)", "W")
+	address, err := resolveValue("EVENT_ADDRESS", "Address", typ.venue.Address)
 	if err != nil {
-		return eventEntry{}, nil, err
+		return nil, nil, err
 	}
-	locDetails, err := resolveOptional("EVENT_LOC_DETAILS", "Location details (optional)")
+	area, err := resolveParsed("EVENT_AREA", "Area code ("+strings.Join(shift2bikesAreas, ", ")+")", typ.venue.Area, parseArea)
 	if err != nil {
-		return eventEntry{}, nil, err
+		return nil, nil, err
 	}
-	start, err := resolveValue("EVENT_START", "Start location (for events.md)", "Beaverton")
+	locDetails, err := resolveOptional("EVENT_LOC_DETAILS", "Location details (optional)")
 	if err != nil {
-		return eventEntry{}, nil, err
+		return nil, nil, err
 	}
-	end, err := resolveValue("EVENT_END", "End location (for events.md)", "Beaverton")
+	start, err := resolveValue("EVENT_START", "Start location (for events.md)", typ.Start)
 	if err != nil {
-		return eventEntry{}, nil, err
+		return nil, nil, err
 	}
-
-	entry := eventEntry{
-		Title: title,
-		Date:  d.display,
-		Start: start,
-		End:   end,
-	}
-
-	payload := &shift2bikesPayload{
-		Title:         title,
-		Details:       details,
-		Audience:      "G",
-		Time:          eventTime,
-		TimeDetails:   timeDetails,
-		Area:          area,
-		Venue:         venue,
-		Address:       address,
-		LocDetails:    locDetails,
-		Length:        "--",
-		Organizer:     "Ride Westside",
-		Email:         "ridewestside2023@gmail.com",
-		HideEmail:     "1",
-		WebName:       "Ride Westside",
-		WebURL:        "https://ridewestside.org",
-		CodeOfConduct: "1",
-		ReadComic:     "1",
-		DateStatuses: []dateStatus{
-			{Date: d.api, Status: "A"},
-		},
-	}
-
-	return entry, payload, nil
+	end, err := resolveValue("EVENT_END", "End location (for events.md)", typ.End)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries := make([]eventEntry, len(dates))
+	times := make([]time.Time, len(dates))
+	for i, d := range dates {
+		entries[i] = eventEntry{
+			Title: title,
+			Date:  d.display,
+			Start: start,
+			End:   end,
+			Tags:  append([]string(nil), typ.Tags...),
+		}
+		times[i] = d.t
+	}
+
+	payload, err := typ.payload(times...)
+	if err != nil || payload == nil {
+		return entries, nil, err
+	}
+	payload.Title = title
+	payload.Details = details
+	payload.Time = eventTime
+	payload.TimeDetails = timeDetails
+	payload.EventDuration = duration
+	payload.Venue = venue
+	payload.Address = address
+	payload.Area = area
+	payload.LocDetails = locDetails
+	return entries, payload, nil
 }
 
 // --- Shift2Bikes integration ---
 
-func resolveShiftMode(defaultMode string) (string, error) {
+func resolveShiftMode(envVar, defaultMode string) (string, error) {
 	modes := []string{"create", "existing", "skip"}
 	labels := []string{"Create new Shift2Bikes event", "Use existing Shift2Bikes URL", "Skip (no Shift2Bikes link)"}
 
-	if v := os.Getenv("EVENT_SHIFT_MODE"); v != "" {
+	if v := os.Getenv(envVar); v != "" {
 		v = strings.ToLower(v)
 		for _, m := range modes {
 			if m == v {
 				return m, nil
 			}
 		}
-		return "", fmt.Errorf("invalid EVENT_SHIFT_MODE %q; use create, existing, or skip", v)
+		return "", fmt.Errorf("invalid %s %q; use create, existing, or skip", envVar, v)
 	}
 	if !isInteractive() {
 		if defaultMode != "" {
 			return defaultMode, nil
 		}
-		return "", fmt.Errorf("non-interactive: set EVENT_SHIFT_MODE environment variable")
+		return "", fmt.Errorf("non-interactive: set %s environment variable", envVar)
 	}
 	idx, err := resolveChoice("", "Shift2Bikes integration", labels)
 	if err != nil {
@@ -495,187 +974,115 @@ func resolveShiftMode(defaultMode string) (string, error) {
 	return modes[idx], nil
 }
 
-func handleShift2Bikes(payload *shift2bikesPayload, typeIdx int) (string, error) {
-	// Default for Tigard: skip unless explicitly set
-	defaultMode := ""
-	if typeIdx == 1 {
-		defaultMode = "skip"
-		if isInteractive() {
-			fmt.Println("Tigard events typically don't use Shift2Bikes.")
-		}
+// handleShift2Bikes links entries, one per date, to Shift2Bikes.
+func handleShift2Bikes(payload *shift2bikesPayload, typ *eventType, entries []eventEntry) error {
+	// Types with shift_mode: skip don't use Shift2Bikes unless explicitly set
+	if typ.ShiftMode == "skip" && isInteractive() {
+		fmt.Printf("%s events typically don't use Shift2Bikes.\n", typ.Label)
 	}
 
-	mode, err := resolveShiftMode(defaultMode)
+	mode, err := resolveShiftMode("EVENT_SHIFT_MODE", typ.ShiftMode)
 	if err != nil {
-		return "", err
+		return err
 	}
-	return handleShiftMode(mode, payload)
+	return handleShiftMode(mode, payload, "EVENT_SHIFT_URL", entries)
 }
 
-func handleShiftMode(mode string, payload *shift2bikesPayload) (string, error) {
+// handleShiftMode sets the URL of each entry: the matching date of a newly
+// created Shift2Bikes event, or the existing URLs from urlEnvVar in order.
+func handleShiftMode(mode string, payload *shift2bikesPayload, urlEnvVar string, entries []eventEntry) error {
 	switch strings.ToLower(mode) {
 	case "create":
 		if payload == nil {
-			return "", fmt.Errorf("no Shift2Bikes payload available for this event type")
+			return fmt.Errorf("no Shift2Bikes payload available for this event type")
 		}
-		url, err := submitToShift2Bikes(payload)
+		urls, err := submitToShift2Bikes(payload)
 		if err != nil {
-			return "", fmt.Errorf("Shift2Bikes API error: %w", err)
+			return fmt.Errorf("Shift2Bikes API error: %w", err)
+		}
+		for i := range entries {
+			entries[i].URL = urls[entryAPIDate(entries[i])]
+			if entries[i].URL == "" {
+				fmt.Printf("WARNING: no Shift2Bikes date ID returned for %s\n", entries[i].Date)
+			}
 		}
 		fmt.Println("\nREMINDER: Check the Ride Westside Gmail for the confirmation email")
 		fmt.Println("and click the link to publish the event on the Shift2Bikes calendar!")
-		return url, nil
+		return nil
 	case "existing":
-		url, err := resolveValue("EVENT_SHIFT_URL", "Existing Shift2Bikes calendar URL", "")
+		prompt := "Existing Shift2Bikes calendar URL"
+		if len(entries) > 1 {
+			prompt = fmt.Sprintf("Existing Shift2Bikes calendar URLs for the %d dates, in order (comma-separated)", len(entries))
+		}
+		raw, err := resolveValue(urlEnvVar, prompt, "")
 		if err != nil {
-			return "", err
+			return err
+		}
+		urls := strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == ' ' })
+		if len(urls) != len(entries) {
+			return fmt.Errorf("%s has %d URLs for %d dates", urlEnvVar, len(urls), len(entries))
 		}
-		return url, nil
+		for i := range entries {
+			entries[i].URL = urls[i]
+		}
+		return nil
 	case "skip":
-		return "", nil
+		return nil
 	default:
-		return "", fmt.Errorf("invalid EVENT_SHIFT_MODE %q; use create, existing, or skip", mode)
+		return fmt.Errorf("invalid EVENT_SHIFT_MODE %q; use create, existing, or skip", mode)
 	}
 }
 
-func submitToShift2Bikes(payload *shift2bikesPayload) (string, error) {
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal payload: %w", err)
+// submitToShift2Bikes creates an event with one or more dates and returns
+// the calendar URL of each date, keyed by YYYY-MM-DD.
+func submitToShift2Bikes(payload *shift2bikesPayload) (map[string]string, error) {
+	// Fail before creating anything if the edit secret could not be kept
+	if err := checkSecretsStorable(); err != nil {
+		return nil, err
 	}
-
-	req, err := http.NewRequest("POST", "https://www.shift2bikes.org/api/manage_event.php", bytes.NewReader(body))
+	event, err := newShift2BikesClient().Create(payload)
 	if err != nil {
-		return "", err
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 15 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+	urls := make(map[string]string)
+	for _, ds := range event.DateStatuses {
+		if ds.ID != "" {
+			urls[ds.Date] = shift2bikesEventURL(ds.ID)
+		}
 	}
-
-	if resp.StatusCode >= 400 {
-		return "", fmt.Errorf("API returned HTTP %d: %s", resp.StatusCode, string(respBody))
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no event ID in API response")
 	}
-
-	fmt.Printf("Shift2Bikes response: %s\n", string(respBody))
-
-	var result shift2bikesResponse
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+	fmt.Printf("Shift2Bikes event %s created (%d dates)\n", event.ID, len(event.DateStatuses))
+	if err := saveShiftSecret(event); err != nil {
+		fmt.Printf("WARNING: %v\nEdit secret for event %s: %s\n", err, event.ID, event.Secret)
 	}
+	return urls, nil
+}
 
-	if len(result.DateStatuses) == 0 || result.DateStatuses[0].ID == "" {
-		return "", fmt.Errorf("no event ID in API response")
+// entryAPIDate returns an entry's date as YYYY-MM-DD, or "" if it has none.
+func entryAPIDate(e eventEntry) string {
+	t, err := time.ParseInLocation(eventDateLayout, e.Date, time.Local)
+	if err != nil {
+		return ""
 	}
-
-	eventID := result.DateStatuses[0].ID
-	return fmt.Sprintf("https://www.shift2bikes.org/calendar/event-%s", eventID), nil
+	return t.Format("2006-01-02")
 }
 
-// --- events.md manipulation ---
+// --- events.md sections ---
 
-func formatEventYAML(entry eventEntry) string {
-	var b strings.Builder
-	fmt.Fprintf(&b, "  - title: %q\n", entry.Title)
-	fmt.Fprintf(&b, "    date: %q\n", entry.Date)
-	if entry.URL != "" {
-		fmt.Fprintf(&b, "    url: %q\n", entry.URL)
-	}
-	if entry.Route != "" {
-		fmt.Fprintf(&b, "    route: %q\n", entry.Route)
+func determineSection(typ *eventType) (string, error) {
+	if typ.Section != "" {
+		return typ.Section, nil
 	}
-	fmt.Fprintf(&b, "    start: %q\n", entry.Start)
-	fmt.Fprintf(&b, "    end: %q", entry.End)
-	if entry.StartAddress != "" {
-		fmt.Fprintf(&b, "\n    start_address: %q", entry.StartAddress)
-	}
-	return b.String()
-}
-
-func appendEventToFile(path string, entry eventEntry, sectionComment string) error {
-	content, err := os.ReadFile(path)
+	section, err := resolveOptional("EVENT_SECTION", "Insert after YAML comment section (optional, press enter to append at end)")
 	if err != nil {
-		return err
-	}
-
-	lines := strings.Split(string(content), "\n")
-	newBlock := "\n" + formatEventYAML(entry)
-	insertIdx := -1
-
-	if sectionComment != "" {
-		// Find the section comment, then scan forward to the last event in that section
-		sectionFound := false
-		for i, line := range lines {
-			trimmed := strings.TrimSpace(line)
-			if strings.Contains(trimmed, sectionComment) {
-				sectionFound = true
-				// Scan forward from here to find the last event entry before next section or end
-				lastEventLine := i
-				for j := i + 1; j < len(lines); j++ {
-					jTrimmed := strings.TrimSpace(lines[j])
-					// Stop at next comment section or closing ---
-					if strings.HasPrefix(jTrimmed, "#") || jTrimmed == "---" {
-						break
-					}
-					// Track lines that are part of event entries
-					if jTrimmed != "" {
-						lastEventLine = j
-					}
-				}
-				insertIdx = lastEventLine + 1
-				break
-			}
-		}
-		if !sectionFound {
-			return fmt.Errorf("section comment %q not found in %s", sectionComment, path)
-		}
-	} else {
-		// Insert before closing ---
-		for i := len(lines) - 1; i >= 0; i-- {
-			if strings.TrimSpace(lines[i]) == "---" {
-				insertIdx = i
-				break
-			}
-		}
-		if insertIdx == -1 {
-			return fmt.Errorf("could not find closing --- in %s", path)
-		}
+		return "", err
 	}
-
-	// Splice in the new block
-	newLines := make([]string, 0, len(lines)+4)
-	newLines = append(newLines, lines[:insertIdx]...)
-	newLines = append(newLines, newBlock)
-	newLines = append(newLines, lines[insertIdx:]...)
-
-	return os.WriteFile(path, []byte(strings.Join(newLines, "\n")), 0644)
-}
-
-func determineSection(typeIdx int) (string, error) {
-	switch typeIdx {
-	case 0:
-		return "# Beaverton Bike Happy Hours", nil
-	case 1:
-		return "# Tigard Happy Hours", nil
-	default:
-		section, err := resolveOptional("EVENT_SECTION", "Insert after YAML comment section (optional, press enter to append at end)")
-		if err != nil {
-			return "", err
-		}
-		if section != "" && !strings.HasPrefix(section, "#") {
-			section = "# " + section
-		}
-		return section, nil
+	if section != "" && !strings.HasPrefix(section, "#") {
+		section = "# " + section
 	}
+	return section, nil
 }
 
 // --- Output helpers ---
@@ -692,6 +1099,9 @@ func printEventSummary(entry eventEntry) {
 	}
 	fmt.Printf("Start: %s\n", entry.Start)
 	fmt.Printf("End:   %s\n", entry.End)
+	if len(entry.Tags) > 0 {
+		fmt.Printf("Tags:  %s\n", strings.Join(entry.Tags, ", "))
+	}
 	fmt.Println("=========================")
 }
 
@@ -712,126 +1122,3 @@ func printSocialTemplates(entry eventEntry) {
 	fmt.Println("#BikeHappyHour #RideWestside")
 	fmt.Println("=================================")
 }
-
-// --- Recurring events ---
-
-// AddRecurringEvents generates recurring happy hour events for a given year.
-// Idempotent: skips events whose titles already exist in events.md.
-//
-// Usage: mage addRecurringEvents 2027
-func AddRecurringEvents(year int) error {
-	eventsPath := "content/events.md"
-
-	existing, err := existingTitles(eventsPath)
-	if err != nil {
-		return err
-	}
-
-	fmt.Printf("Generating recurring events for %d...\n", year)
-
-	added, skipped := 0, 0
-
-	// Beaverton Happy Hours: 2nd and 4th Monday
-	fmt.Println("\nBeaverton Happy Hours:")
-	for month := time.January; month <= time.December; month++ {
-		for _, n := range []int{2, 4} {
-			d := nthWeekday(year, month, time.Monday, n)
-			if d.IsZero() {
-				continue
-			}
-			short := fmt.Sprintf("%d/%d", int(d.Month()), d.Day())
-			entry := eventEntry{
-				Title:        fmt.Sprintf("%s Bike Happy Hour", short),
-				Date:         d.Format("January 2, 2006"),
-				Start:        "Beaverton",
-				End:          "Beaverton",
-				StartAddress: "4250 SW Rose Biggi Ave, Beaverton, OR",
-			}
-			if existing[entry.Title] {
-				fmt.Printf("  = %s (%s) already exists, skipped\n", entry.Title, entry.Date)
-				skipped++
-				continue
-			}
-			if err := appendEventToFile(eventsPath, entry, "# Beaverton Bike Happy Hours"); err != nil {
-				return fmt.Errorf("failed to add %s: %w", entry.Title, err)
-			}
-			existing[entry.Title] = true
-			fmt.Printf("  + %s (%s)\n", entry.Title, entry.Date)
-			added++
-		}
-	}
-
-	// Tigard Happy Hours: 1st and 3rd Tuesday
-	fmt.Println("\nTigard Happy Hours:")
-	for month := time.January; month <= time.December; month++ {
-		for _, n := range []int{1, 3} {
-			d := nthWeekday(year, month, time.Tuesday, n)
-			if d.IsZero() {
-				continue
-			}
-			short := fmt.Sprintf("%d/%d", int(d.Month()), d.Day())
-			entry := eventEntry{
-				Title: fmt.Sprintf("%s Tigard Happy Hour", short),
-				Date:  d.Format("January 2, 2006"),
-				Start: "Tigard",
-				End:   "Tigard",
-			}
-			if existing[entry.Title] {
-				fmt.Printf("  = %s (%s) already exists, skipped\n", entry.Title, entry.Date)
-				skipped++
-				continue
-			}
-			if err := appendEventToFile(eventsPath, entry, "# Tigard Happy Hours"); err != nil {
-				return fmt.Errorf("failed to add %s: %w", entry.Title, err)
-			}
-			existing[entry.Title] = true
-			fmt.Printf("  + %s (%s)\n", entry.Title, entry.Date)
-			added++
-		}
-	}
-
-	fmt.Printf("\nAdded %d events, skipped %d.\n", added, skipped)
-	return nil
-}
-
-// nthWeekday returns the nth occurrence of a weekday in the given month/year.
-// Returns zero time if the nth occurrence doesn't exist in that month.
-func nthWeekday(year int, month time.Month, weekday time.Weekday, n int) time.Time {
-	// Start at the 1st of the month
-	first := time.Date(year, month, 1, 0, 0, 0, 0, time.Local)
-
-	// Find the first occurrence of the target weekday
-	offset := int(weekday) - int(first.Weekday())
-	if offset < 0 {
-		offset += 7
-	}
-	firstOccurrence := first.AddDate(0, 0, offset)
-
-	// Jump to the nth occurrence
-	target := firstOccurrence.AddDate(0, 0, (n-1)*7)
-
-	// Verify it's still in the same month
-	if target.Month() != month {
-		return time.Time{}
-	}
-	return target
-}
-
-// existingTitles reads events.md and returns a set of all event titles.
-func existingTitles(path string) (map[string]bool, error) {
-	content, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-
-	titles := make(map[string]bool)
-	titleRegex := regexp.MustCompile(`^\s*-\s*title:\s*"([^"]+)"`)
-
-	for _, line := range strings.Split(string(content), "\n") {
-		if m := titleRegex.FindStringSubmatch(line); m != nil {
-			titles[m[1]] = true
-		}
-	}
-
-	return titles, nil
-}
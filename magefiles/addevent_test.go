@@ -0,0 +1,185 @@
+//go:build mage
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// testNow is a Wednesday afternoon late in the year, so dates without a year
+// can roll over into the next one.
+var testNow = time.Date(2026, time.December, 16, 15, 4, 0, 0, time.Local)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string // YYYY-MM-DD, or "" for an error
+	}{
+		// Explicit dates, past ones included
+		{"1/12/2026", "2026-01-12"},
+		{"2026-3-9", "2026-03-09"},
+		{"2028-02-29", "2028-02-29"},
+		{"March 9th, 2026", "2026-03-09"},
+
+		// M/D and month names are the next one on or after today
+		{"12/16", "2026-12-16"},
+		{"12/15", "2027-12-15"},
+		{"1/12", "2027-01-12"},
+		{"2/29", "2028-02-29"},
+		{"Dec. 20", "2026-12-20"},
+		{"march 9", "2027-03-09"},
+
+		{"today", "2026-12-16"},
+		{"Tomorrow", "2026-12-17"},
+
+		// Weekdays: today counts unless it says next
+		{"wednesday", "2026-12-16"},
+		{"this friday", "2026-12-18"},
+		{"monday", "2026-12-21"},
+		{"next wednesday", "2026-12-23"},
+		{"Next Mon", "2026-12-21"},
+
+		// Ordinals
+		{"2nd monday of march", "2027-03-08"},
+		{"2nd monday of december", "2027-12-13"},
+		{"3rd wednesday of december", "2026-12-16"},
+		{"last friday in january 2027", "2027-01-29"},
+		{"first tue of jan", "2027-01-05"},
+		{"5th monday of february 2027", ""},
+		{"2nd monday of smarch", ""},
+		{"3rd blursday of march", ""},
+
+		// Invalid
+		{"", ""},
+		{"someday", ""},
+		{"13/1", ""},
+		{"2/30", ""},
+		{"2/30/2026", ""},
+		{"2026-02-29", ""},
+		{"2026-13-01", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := parseDate(tt.raw, testNow)
+			switch {
+			case tt.want == "" && err == nil:
+				t.Errorf("parseDate(%q) = %s, want an error", tt.raw, got.api)
+			case tt.want != "" && err != nil:
+				t.Errorf("parseDate(%q): %v", tt.raw, err)
+			case tt.want != "" && got.api != tt.want:
+				t.Errorf("parseDate(%q) = %s, want %s", tt.raw, got.api, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDates(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string // space-separated YYYY-MM-DD
+		wantErr string
+	}{
+		{raw: "1/12, 1/26", want: "2027-01-12 2027-01-26"},
+		{raw: "January 12, 2027, January 26, 2027", want: "2027-01-12 2027-01-26"},
+		{raw: "12/28,1/11", want: "2026-12-28 2027-01-11"},
+		{raw: "1/12, 2027-01-12", wantErr: "given twice"},
+		{raw: "1/12, bogus", wantErr: "invalid date"},
+		{raw: " , ", wantErr: "no date given"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			dates, err := parseDates(tt.raw, testNow)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("parseDates(%q) error = %v, want %q", tt.raw, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, d := range dates {
+				got = append(got, d.api)
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("parseDates(%q) = %v, want %s", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEventTime(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string // "" for an error
+	}{
+		{"18:30", "18:30:00"},
+		{"18:30:15", "18:30:15"},
+		{"0:05", "00:05:00"},
+		{"6:30pm", "18:30:00"},
+		{"6 PM", "18:00:00"},
+		{"6:30 p.m.", "18:30:00"},
+		{"10a", "10:00:00"},
+		{"12am", "00:00:00"},
+		{"12pm", "12:00:00"},
+
+		{"6", ""},
+		{"24:00", ""},
+		{"13pm", ""},
+		{"0am", ""},
+		{"6:60pm", ""},
+		{"18:30:60", ""},
+		{"noon", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := parseEventTime(tt.raw)
+			switch {
+			case tt.want == "" && err == nil:
+				t.Errorf("parseEventTime(%q) = %s, want an error", tt.raw, got)
+			case tt.want != "" && err != nil:
+				t.Errorf("parseEventTime(%q): %v", tt.raw, err)
+			case got != tt.want:
+				t.Errorf("parseEventTime(%q) = %s, want %s", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEventDuration(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string // minutes, or "" for an error
+	}{
+		{"2h", "120"},
+		{"90m", "90"},
+		{"1h30m", "90"},
+		{" 2H ", "120"},
+		{"45", "45"},
+		{"23h59m", "1439"},
+
+		{"0", ""},
+		{"-5", ""},
+		{"90s", ""},
+		{"24h", ""},
+		{"2 hours", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := parseEventDuration(tt.raw)
+			switch {
+			case tt.want == "" && err == nil:
+				t.Errorf("parseEventDuration(%q) = %s, want an error", tt.raw, got)
+			case tt.want != "" && err != nil:
+				t.Errorf("parseEventDuration(%q): %v", tt.raw, err)
+			case got != tt.want:
+				t.Errorf("parseEventDuration(%q) = %s, want %s", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
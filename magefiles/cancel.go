@@ -0,0 +1,101 @@
+//go:build mage
+
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// CancelEvent cancels one date of an event: the date is marked cancelled on
+// Shift2Bikes (when its edit secret is stored) and the entry in events.md
+// gets status: cancelled so the site shows it struck through.
+//
+// Environment variables:
+//
+//	EVENT_SELECT       event title, date (MM/DD, MM/DD/YYYY) or Shift2Bikes URL
+//	EVENT_REASON       reason shown on the site and as the Shift2Bikes newsflash (optional)
+//	EVENT_SHIFT_CANCEL yes to also cancel on Shift2Bikes (default yes when a secret is stored)
+//	EVENT_CONFIRM      yes to skip confirmation prompt
+//
+// Set DRY_RUN=1 to print the events.md diff without calling the API or writing.
+func CancelEvent() error {
+	doc, err := loadEventsDoc(eventsFile)
+	if err != nil {
+		return err
+	}
+
+	ev, err := resolveEvent(doc, "EVENT_SELECT", "Event to cancel (title, date or Shift2Bikes URL)")
+	if err != nil {
+		return err
+	}
+	if ev.Status == eventStatusCancelled {
+		return fmt.Errorf("%q (%s) is already cancelled", ev.Title, ev.Date)
+	}
+
+	reason, err := resolveOptional("EVENT_REASON", "Reason (optional, e.g. \"Rained out\")")
+	if err != nil {
+		return err
+	}
+
+	// Find the Shift2Bikes secret, if this event was created through addEvent
+	var secret *shiftSecret
+	if ev.URL != "" && shift2bikesEventRegex.MatchString(ev.URL) {
+		store, err := loadShiftSecrets()
+		if err != nil {
+			return err
+		}
+		if rec, ok := store.Lookup(ev.URL); ok {
+			secret = &rec
+		} else {
+			fmt.Printf("No stored edit secret for %s; cancel it on Shift2Bikes by hand.\n", ev.URL)
+		}
+	}
+	cancelShift := false
+	if secret != nil {
+		if cancelShift, err = resolveConfirmDefault("EVENT_SHIFT_CANCEL", "Also cancel on Shift2Bikes?", true); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("\n===== CANCEL EVENT =====")
+	fmt.Printf("Title:  %s\n", ev.Title)
+	fmt.Printf("Date:   %s\n", ev.Date)
+	if reason != "" {
+		fmt.Printf("Reason: %s\n", reason)
+	}
+	if cancelShift {
+		fmt.Printf("Shift2Bikes: cancel %s\n", ev.URL)
+	}
+	fmt.Println("========================")
+
+	confirmed, err := resolveConfirm("EVENT_CONFIRM", "\nCancel this event?")
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		fmt.Println("Aborted.")
+		return nil
+	}
+
+	if cancelShift && !dryRun() {
+		d, err := time.Parse(eventDateLayout, ev.Date)
+		if err != nil {
+			return fmt.Errorf("invalid date %q: %w", ev.Date, err)
+		}
+		if _, err := newShift2BikesClient().CancelDate(secret.EventID, secret.Secret, d.Format("2006-01-02"), reason); err != nil {
+			return fmt.Errorf("Shift2Bikes API error: %w", err)
+		}
+		fmt.Printf("Cancelled %s on Shift2Bikes\n", d.Format("2006-01-02"))
+	}
+
+	ev.Status = eventStatusCancelled
+	ev.Reason = reason
+	if err := doc.Commit(); err != nil {
+		return err
+	}
+	if !dryRun() {
+		fmt.Printf("Marked %q cancelled in %s\n", ev.Title, eventsFile)
+	}
+	return nil
+}
@@ -3,12 +3,14 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
-	"path/filepath"
+	neturl "net/url"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -16,99 +18,174 @@ import (
 	"github.com/magefile/mage/mg"
 )
 
-// CheckLinks checks for dead links in the built site
+// CheckLinks checks for dead links in the built site: external links over
+// HTTP, and relative links, assets and #anchors against the files in public/.
+// Each problem is reported with the pages, elements and content/*.md lines it
+// comes from.
+//
+// Environment variables:
+//
+//	LINKS_REPORT_JSON      path to write every result as JSON
+//	LINKS_REPORT_JUNIT     path to write a JUnit XML report, one test case per link
+//	LINKS_REPORT_MARKDOWN  path to append a Markdown summary to, e.g. $GITHUB_STEP_SUMMARY
+//	LINKS_RETRIES          extra attempts after a network error, 429 or 5xx (default 2)
+//	LINKS_BACKOFF          wait before the first retry, doubling after each (default 1s)
+//	LINKS_MAX_WAIT         longest wait between attempts; a longer Retry-After
+//	                       gives up on the link (default 30s)
+//	LINKS_TIMEOUT          timeout for each attempt (default 10s)
+//	LINKS_PER_HOST         requests in flight to one host (default 2)
+//	LINKS_CACHE            cache of earlier results (default .cache/links.json)
+//	LINKS_CACHE_TTL        how long an ok result is trusted without a request
+//	                       (default 24h); older ones are re-checked with
+//	                       If-None-Match/If-Modified-Since
+//	LINKS_FORCE            1 to re-check every link, ignoring the cache
+//
+// Links that still fail after their retries with a timeout, 429 or 5xx are
+// reported as transient; only confirmed-dead links fail the target.
 func CheckLinks() error {
-	mg.Deps(Build)
+	cfg, err := linkCheckConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	cache, err := loadLinkCache()
+	if err != nil {
+		return err
+	}
 
-	fmt.Println("\nChecking for dead links...")
+	mg.Deps(ValidateEvents, Build)
 
-	links, err := extractLinks("public")
+	idx, err := scanSite("public")
 	if err != nil {
 		return fmt.Errorf("failed to extract links: %w", err)
 	}
+	content, err := loadContentIndex("content")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\nChecking internal links and assets...")
+
+	results := checkInternalLinks(idx)
+	broken := 0
+	for _, r := range results {
+		if !r.OK() {
+			broken++
+		}
+	}
+	if broken == 0 {
+		fmt.Printf("✓ All %d internal links, assets and anchors resolve\n", len(results))
+	}
+
+	fmt.Println("\nChecking for dead links...")
+
+	external := idx.ExternalLinks()
+	links := make([]string, 0, len(external))
+	for link := range external {
+		links = append(links, link)
+	}
+	sort.Strings(links)
 
 	if len(links) == 0 {
 		fmt.Println("No external links found.")
-		return nil
+	} else {
+		fmt.Printf("Found %d unique external links to check\n\n", len(links))
+		checked := checkLinksParallel(cfg, cache, links)
+		if err := cache.Save(checked); err != nil {
+			return err
+		}
+		for _, r := range checked {
+			r.Sources = external[r.URL]
+			results = append(results, r)
+		}
 	}
 
-	fmt.Printf("Found %d unique external links to check\n\n", len(links))
+	var deadLinks, transient []*linkResult
+	for i := range results {
+		r := &results[i]
+		if r.OK() {
+			continue
+		}
+		r.Content = content.Find(r.searchURLs()...)
+		if r.Transient {
+			transient = append(transient, r)
+		} else {
+			deadLinks = append(deadLinks, r)
+		}
+	}
 
-	deadLinks := checkLinksParallel(links)
+	if err := writeLinkReports(results); err != nil {
+		return err
+	}
 
+	if len(transient) > 0 {
+		fmt.Printf("\n⚠ %d links could not be checked and may be temporarily down:\n", len(transient))
+		printLinkResults(transient)
+	}
 	if len(deadLinks) > 0 {
 		fmt.Printf("\n❌ Found %d dead or problematic links:\n", len(deadLinks))
-		for _, dl := range deadLinks {
-			fmt.Printf("  • %s\n    Status: %s\n", dl.URL, dl.Status)
-		}
+		printLinkResults(deadLinks)
 		return fmt.Errorf("found %d dead links", len(deadLinks))
 	}
 
+	if len(transient) > 0 {
+		fmt.Println("\n✓ No confirmed dead links")
+		return nil
+	}
 	fmt.Println("\n✓ All links are valid!")
 	return nil
 }
 
-type deadLink struct {
-	URL    string
-	Status string
-}
-
-func extractLinks(dir string) ([]string, error) {
-	linkSet := make(map[string]bool)
-	// Match <a href="..."> links, excluding preconnect/dns-prefetch hints
-	anchorRegex := regexp.MustCompile(`<a\s[^>]*href=["']?(https?://[^"'\s>]+)["']?`)
-	// Also match links in data attributes for tracking purposes
-	dataRegex := regexp.MustCompile(`data-track[^>]*href=["']?(https?://[^"'\s>]+)["']?`)
-
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+func printLinkResults(results []*linkResult) {
+	for _, r := range results {
+		fmt.Printf("  • %s\n    Status: %s\n", r.URL, r.Status)
+		for _, src := range r.Sources {
+			fmt.Printf("    Found on: %s\n", src)
 		}
-
-		if info.IsDir() || !strings.HasSuffix(path, ".html") {
-			return nil
+		for _, line := range r.Content {
+			fmt.Printf("    Source:   %s\n", line)
 		}
+	}
+}
 
-		content, err := os.ReadFile(path)
-		if err != nil {
-			return err
-		}
+// linkResult is the outcome of checking one URL.
+type linkResult struct {
+	URL          string
+	Internal     bool          // checked against public/ rather than fetched
+	Status       string        // why the link is dead; empty when it is fine or skipped
+	Transient    bool          // Status is an error that may clear up, e.g. a timeout or 503
+	HTTPStatus   int           // final response code, when there was one
+	Attempts     int           // requests made, including retries
+	Latency      time.Duration // time spent on the last attempt
+	Redirects    []string      // URLs redirected through, in order
+	Cached       bool          // ok per the cache, without a request or after a 304
+	ETag         string        // validators from the response, for the cache
+	LastModified string
+	Skipped      string      // why the link was not checked
+	Sources      []*pageLink // where the URL appears in public/
+	Content      []string    // content/*.md lines it comes from, as file:line
+}
 
-		contentStr := string(content)
+// OK reports whether the link is fine or was skipped.
+func (r *linkResult) OK() bool {
+	return r.Status == ""
+}
 
-		// Extract anchor tag links
-		matches := anchorRegex.FindAllStringSubmatch(contentStr, -1)
-		for _, match := range matches {
-			if len(match) > 1 {
-				url := strings.TrimSuffix(match[1], `"`)
-				url = strings.TrimSuffix(url, `'`)
-				linkSet[url] = true
-			}
-		}
+// Dead reports whether the link is confirmed broken, as opposed to fine or
+// failing in a way that may clear up.
+func (r *linkResult) Dead() bool {
+	return r.Status != "" && !r.Transient
+}
 
-		// Extract data attribute links
-		matches = dataRegex.FindAllStringSubmatch(contentStr, -1)
-		for _, match := range matches {
-			if len(match) > 1 {
-				url := strings.TrimSuffix(match[1], `"`)
-				url = strings.TrimSuffix(url, `'`)
-				linkSet[url] = true
-			}
+// searchURLs is what to look for in content/*.md: the URL, then the values
+// as written in the pages when they differ (e.g. relative paths).
+func (r *linkResult) searchURLs() []string {
+	urls := []string{r.URL}
+	for _, src := range r.Sources {
+		if !slices.Contains(urls, src.URL) {
+			urls = append(urls, src.URL)
 		}
-
-		return nil
-	})
-
-	if err != nil {
-		return nil, err
 	}
-
-	links := make([]string, 0, len(linkSet))
-	for link := range linkSet {
-		links = append(links, link)
-	}
-
-	return links, nil
+	return urls
 }
 
 // skipDomains contains domains with aggressive bot protection that return
@@ -127,106 +204,199 @@ func shouldSkipDomain(url string) bool {
 	return false
 }
 
-func checkLinksParallel(links []string) []deadLink {
+// linkChecker holds what the checks of external links share.
+type linkChecker struct {
+	cfg       linkCheckConfig
+	client    *http.Client
+	limiter   *hostLimiter
+	semaphore chan struct{} // Limit concurrent requests
+	cache     *linkCache
+}
+
+// checkLinksParallel checks every link and returns the results in the same
+// order, retrying the ones that fail in a way that may clear up. Links that
+// were ok within the cache TTL are not fetched again.
+func checkLinksParallel(cfg linkCheckConfig, cache *linkCache, links []string) []linkResult {
 	var (
-		deadLinks []deadLink
-		mu        sync.Mutex
-		wg        sync.WaitGroup
-		semaphore = make(chan struct{}, 5) // Limit concurrent requests
+		results = make([]linkResult, len(links))
+		wg      sync.WaitGroup
 	)
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			if len(via) >= 3 {
-				return fmt.Errorf("too many redirects")
-			}
-			return nil
+	c := &linkChecker{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= 3 {
+					return errTooManyRedirects
+				}
+				return nil
+			},
 		},
+		limiter:   newHostLimiter(cfg.PerHost),
+		semaphore: make(chan struct{}, cfg.Concurrency),
+		cache:     cache,
 	}
 
-	for _, link := range links {
+	for i, link := range links {
 		wg.Add(1)
-		go func(url string) {
+		go func(i int, url string) {
 			defer wg.Done()
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
 
 			// Skip domains with aggressive bot protection
 			if shouldSkipDomain(url) {
+				results[i] = linkResult{URL: url, Skipped: "bot protection"}
 				fmt.Printf("  ⊘ %s (skipped - bot protection)\n", url)
 				return
 			}
 
-			status := checkLink(client, url)
-			if status != "" {
-				mu.Lock()
-				deadLinks = append(deadLinks, deadLink{URL: url, Status: status})
-				mu.Unlock()
+			if e := cache.fresh(url); e != nil {
+				results[i] = linkResult{URL: url, HTTPStatus: e.HTTPStatus, Cached: true}
+				fmt.Printf("  ✓ %s (cached)\n", url)
+				return
+			}
+
+			r := c.checkWithRetries(url)
+			results[i] = r
+			switch {
+			case r.Skipped != "":
+				fmt.Printf("  ⊘ %s (skipped - %s)\n", url, r.Skipped)
+			case r.Dead():
 				fmt.Printf("  ❌ %s\n", url)
-			} else {
+			case r.Transient:
+				fmt.Printf("  ⚠ %s (%s)\n", url, r.Status)
+			default:
 				fmt.Printf("  ✓ %s\n", url)
 			}
-		}(link)
+		}(i, link)
 	}
 
 	wg.Wait()
-	return deadLinks
+	return results
 }
 
-// shift2bikes event URL pattern: https://shift2bikes.org/calendar/event-XXXXX
-var shift2bikesEventRegex = regexp.MustCompile(`^https?://shift2bikes\.org/calendar/event-(\d+)`)
+// checkWithRetries checks url until it is ok or dead, or the retries run
+// out, backing off between attempts. Each attempt waits for a slot for its
+// host and then for a global one.
+func (c *linkChecker) checkWithRetries(url string) linkResult {
+	host := url
+	if u, err := neturl.Parse(url); err == nil {
+		host = u.Host
+	}
+
+	for attempt := 0; ; attempt++ {
+		release := c.limiter.acquire(host)
+		c.semaphore <- struct{}{}
+		start := time.Now()
+		r, after := c.check(url)
+		r.Latency = time.Since(start)
+		r.Attempts = attempt + 1
+		<-c.semaphore
+		release()
+
+		if !r.Transient {
+			return r
+		}
+		if after > 0 {
+			c.limiter.pause(host, min(after, c.cfg.MaxWait))
+		}
+		if attempt == c.cfg.Retries || after > c.cfg.MaxWait {
+			if r.Attempts > 1 {
+				r.Status += fmt.Sprintf(" (after %d attempts)", r.Attempts)
+			}
+			return r
+		}
+		if after == 0 {
+			time.Sleep(c.cfg.backoff(attempt))
+		}
+	}
+}
+
+// shift2bikes event URL pattern: https://(www.)shift2bikes.org/calendar/event-XXXXX
+var shift2bikesEventRegex = regexp.MustCompile(`^https?://(?:www\.)?shift2bikes\.org/calendar/event-(\d+)`)
+
+// check makes one attempt at url. A result marked Transient may be worth
+// retrying, after the returned Retry-After wait when the server sent one.
+func (c *linkChecker) check(url string) (linkResult, time.Duration) {
+	result := linkResult{URL: url}
 
-func checkLink(client *http.Client, url string) string {
 	// For shift2bikes event pages, check the API directly since the
 	// page is a client-side SPA that won't show errors in raw HTML
 	if m := shift2bikesEventRegex.FindStringSubmatch(url); m != nil {
-		return checkShift2bikesEvent(client, m[1])
+		err := checkShift2bikesEvent(c.client, m[1])
+		if err == nil {
+			return result, 0
+		}
+		// New events stay off the public calendar until their confirmation
+		// link is clicked; checkUnpublished reports those.
+		if errors.Is(err, errShift2BikesNotFound) {
+			result.Skipped = "not yet published on Shift2Bikes"
+			return result, 0
+		}
+		result.Status = fmt.Sprintf("shift2bikes event %s is invalid (%v)", m[1], err)
+		var apiErr *shift2bikesError
+		if errors.As(err, &apiErr) {
+			result.HTTPStatus = apiErr.StatusCode
+		}
+		result.Transient = transientShift2BikesError(err)
+		return result, 0
 	}
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return fmt.Sprintf("invalid URL: %v", err)
+		result.Status = fmt.Sprintf("invalid URL: %v", err)
+		return result, 0
 	}
 
 	// Use a realistic browser User-Agent to avoid being blocked
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
+	c.cache.validators(url, req)
 
-	resp, err := client.Do(req)
+	resp, err := c.client.Do(req)
 	if err != nil {
-		return fmt.Sprintf("request failed: %v", err)
+		result.Status = fmt.Sprintf("request failed: %v", err)
+		result.Transient = retryableError(err)
+		return result, 0
 	}
 	defer resp.Body.Close()
 	io.Copy(io.Discard, resp.Body)
 
+	result.HTTPStatus = resp.StatusCode
+	result.Redirects = redirectChain(resp)
+	result.ETag = resp.Header.Get("ETag")
+	result.LastModified = resp.Header.Get("Last-Modified")
+	if resp.StatusCode == http.StatusNotModified {
+		result.Cached = true
+		return result, 0
+	}
+
 	// Consider 2xx and 3xx as valid
 	if resp.StatusCode >= 400 {
-		return fmt.Sprintf("HTTP %d", resp.StatusCode)
+		result.Status = fmt.Sprintf("HTTP %d", resp.StatusCode)
+		if retryableStatus(resp.StatusCode) {
+			result.Transient = true
+			return result, retryAfter(resp)
+		}
 	}
 
-	return ""
+	return result, 0
 }
 
-func checkShift2bikesEvent(client *http.Client, eventID string) string {
-	apiURL := "https://shift2bikes.org/api/events.php?id=" + eventID
-	req, err := http.NewRequest("GET", apiURL, nil)
-	if err != nil {
-		return fmt.Sprintf("invalid URL: %v", err)
-	}
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Sprintf("request failed: %v", err)
-	}
-	defer resp.Body.Close()
-	io.Copy(io.Discard, resp.Body)
-
-	if resp.StatusCode >= 400 {
-		return fmt.Sprintf("shift2bikes event %s is invalid (API returned HTTP %d)", eventID, resp.StatusCode)
+// redirectChain returns the URLs a response was redirected through, from
+// the first redirect target to the final URL.
+func redirectChain(resp *http.Response) []string {
+	var chain []string
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		chain = append([]string{req.URL.String()}, chain...)
 	}
+	return chain
+}
 
-	return ""
+func checkShift2bikesEvent(client *http.Client, eventID string) error {
+	s2b := newShift2BikesClient()
+	s2b.HTTP = client
+	_, err := s2b.Fetch(eventID)
+	return err
 }
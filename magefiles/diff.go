@@ -0,0 +1,119 @@
+//go:build mage
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContext = 3
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	a, b int  // line positions in the old and new text
+	text string
+}
+
+// unifiedDiff returns a unified diff between two versions of a file, or ""
+// when they are identical.
+func unifiedDiff(name, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+	ops := diffLines(strings.Split(oldText, "\n"), strings.Split(newText, "\n"))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", name, name)
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		start := max(0, i-diffContext)
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j
+			} else if j-end > 2*diffContext {
+				break
+			}
+		}
+		stop := min(len(ops), end+diffContext+1)
+
+		aCount, bCount := 0, 0
+		for _, op := range ops[start:stop] {
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+		}
+		aStart, bStart := ops[start].a+1, ops[start].b+1
+		if aCount == 0 {
+			aStart--
+		}
+		if bCount == 0 {
+			bStart--
+		}
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+		for _, op := range ops[start:stop] {
+			fmt.Fprintf(&b, "%c%s\n", op.kind, op.text)
+		}
+		i = stop
+	}
+	return b.String()
+}
+
+// diffLines computes a line diff using a longest-common-subsequence table
+// over the lines between the common prefix and suffix.
+func diffLines(x, y []string) []diffOp {
+	pre := 0
+	for pre < len(x) && pre < len(y) && x[pre] == y[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(x)-pre && suf < len(y)-pre && x[len(x)-1-suf] == y[len(y)-1-suf] {
+		suf++
+	}
+	xm, ym := x[pre:len(x)-suf], y[pre:len(y)-suf]
+
+	lcs := make([][]int, len(xm)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(ym)+1)
+	}
+	for i := len(xm) - 1; i >= 0; i-- {
+		for j := len(ym) - 1; j >= 0; j-- {
+			if xm[i] == ym[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	for i := 0; i < pre; i++ {
+		ops = append(ops, diffOp{' ', i, i, x[i]})
+	}
+	i, j := 0, 0
+	for i < len(xm) || j < len(ym) {
+		switch {
+		case i < len(xm) && j < len(ym) && xm[i] == ym[j]:
+			ops = append(ops, diffOp{' ', pre + i, pre + j, xm[i]})
+			i++
+			j++
+		case i < len(xm) && (j == len(ym) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', pre + i, pre + j, xm[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', pre + i, pre + j, ym[j]})
+			j++
+		}
+	}
+	for k := 0; k < suf; k++ {
+		ops = append(ops, diffOp{' ', len(x) - suf + k, len(y) - suf + k, x[len(x)-suf+k]})
+	}
+	return ops
+}
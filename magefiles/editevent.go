@@ -0,0 +1,267 @@
+//go:build mage
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// EditEvent changes an existing entry in events.md in place. Every prompt
+// shows the current value as its default; only fields that change are
+// rewritten, so comments and formatting around the entry are kept.
+//
+// Environment variables (unset keeps the current value, "-" clears it):
+//
+//	EVENT_SELECT        event title, date (MM/DD, MM/DD/YYYY) or Shift2Bikes URL
+//	EVENT_TITLE         new title
+//	EVENT_DATE          new date, MM/DD or MM/DD/YYYY
+//	EVENT_SHIFT_URL     Shift2Bikes calendar URL
+//	EVENT_ROUTE         RideWithGPS route URL
+//	EVENT_START         start location
+//	EVENT_END           end location
+//	EVENT_START_ADDRESS street address for map navigation
+//	EVENT_TAGS          comma-separated tags
+//	EVENT_SHIFT_UPDATE  yes to push date/address changes to Shift2Bikes (default yes when a secret is stored)
+//	EVENT_CONFIRM       yes to skip confirmation prompt
+//
+// Set DRY_RUN=1 to print the events.md diff without calling the API or writing.
+func EditEvent() error {
+	doc, err := loadEventsDoc(eventsFile)
+	if err != nil {
+		return err
+	}
+
+	ev, err := resolveEvent(doc, "EVENT_SELECT", "Event to edit (title, date or Shift2Bikes URL)")
+	if err != nil {
+		return err
+	}
+	before := ev.eventEntry
+	after := before
+
+	if after.Title, err = resolveEditValue("EVENT_TITLE", "Title", before.Title); err != nil {
+		return err
+	}
+	date, err := resolveEditValue("EVENT_DATE", "Date (MM/DD/YYYY)", dateInputFormat(before.Date))
+	if err != nil {
+		return err
+	}
+	if date != dateInputFormat(before.Date) {
+		d, err := parseDate(date, time.Now())
+		if err != nil {
+			return err
+		}
+		after.Date = d.display
+		// Keep the "M/D " title prefix in step with the date
+		if after.Title == before.Title {
+			if m := titleDateRegex.FindStringSubmatch(after.Title); m != nil {
+				after.Title = d.short + after.Title[len(m[0])-1:]
+			}
+		}
+	}
+	if after.URL, err = resolveEditValue("EVENT_SHIFT_URL", "Shift2Bikes URL", before.URL); err != nil {
+		return err
+	}
+	if after.Route, err = resolveEditValue("EVENT_ROUTE", "RideWithGPS route URL", before.Route); err != nil {
+		return err
+	}
+	if after.Start, err = resolveEditValue("EVENT_START", "Start location", before.Start); err != nil {
+		return err
+	}
+	if after.End, err = resolveEditValue("EVENT_END", "End location", before.End); err != nil {
+		return err
+	}
+	if after.StartAddress, err = resolveEditValue("EVENT_START_ADDRESS", "Start address", before.StartAddress); err != nil {
+		return err
+	}
+	if os.Getenv("EVENT_TAGS") == "-" {
+		after.Tags = nil
+	} else if after.Tags, err = resolveTags("EVENT_TAGS", before.Tags); err != nil {
+		return err
+	}
+
+	var changes []string
+	for _, key := range eventFieldOrder {
+		if old, cur := before.fieldValue(key), after.fieldValue(key); old != cur {
+			changes = append(changes, fmt.Sprintf("%-13s %s -> %s", key+":", orNone(old), orNone(cur)))
+		}
+	}
+	if len(changes) == 0 {
+		fmt.Println("No changes.")
+		return nil
+	}
+
+	// Reject edits that add a problem anywhere in events.md: a duplicate is
+	// reported against the other entry. Problems that were already there
+	// don't block the edit.
+	existing := make(map[string]bool)
+	for _, p := range validateEventsDoc(doc) {
+		existing[fmt.Sprintf("%d %s", p.Line, p.Message)] = true
+	}
+	ev.eventEntry = after
+	var problems []string
+	for _, p := range validateEventsDoc(doc) {
+		if !existing[fmt.Sprintf("%d %s", p.Line, p.Message)] {
+			problems = append(problems, fmt.Sprintf("%s:%d: %s: %s", eventsFile, p.Line, p.Title, p.Message))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("edit would make %s invalid:\n  %s", eventsFile, strings.Join(problems, "\n  "))
+	}
+
+	// Date and address changes can be pushed to the linked Shift2Bikes event
+	var secret *shiftSecret
+	shiftChanged := after.Date != before.Date || after.StartAddress != before.StartAddress
+	if shiftChanged && after.URL != "" && shift2bikesEventRegex.MatchString(after.URL) {
+		store, err := loadShiftSecrets()
+		if err != nil {
+			return err
+		}
+		if rec, ok := store.Lookup(after.URL); ok {
+			secret = &rec
+		} else {
+			fmt.Printf("No stored edit secret for %s; update it on Shift2Bikes by hand.\n", after.URL)
+		}
+	}
+	pushShift := false
+	if secret != nil {
+		if pushShift, err = resolveConfirmDefault("EVENT_SHIFT_UPDATE", "Also update the Shift2Bikes event?", true); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("\n===== EDIT EVENT =====")
+	fmt.Printf("%s (%s:%d)\n", before.Title, eventsFile, ev.Line)
+	for _, c := range changes {
+		fmt.Println("  " + c)
+	}
+	if pushShift {
+		fmt.Printf("Shift2Bikes: update %s\n", after.URL)
+	}
+	fmt.Println("======================")
+
+	confirmed, err := resolveConfirm("EVENT_CONFIRM", "\nSave these changes?")
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		fmt.Println("Aborted.")
+		return nil
+	}
+
+	if pushShift && !dryRun() {
+		updated, err := pushEventEdit(*secret, before, after)
+		if err != nil {
+			return fmt.Errorf("Shift2Bikes API error: %w", err)
+		}
+		fmt.Printf("Updated Shift2Bikes event %s\n", secret.EventID)
+		// A moved date gets a new per-date id, and the old URL stops working
+		if after.Date != before.Date {
+			newDate, err := time.Parse(eventDateLayout, after.Date)
+			if err != nil {
+				return err
+			}
+			dateID := updated.DateID(newDate.Format("2006-01-02"))
+			if dateID == "" {
+				return fmt.Errorf("Shift2Bikes event %s has no id for %s; events.md was not updated", secret.EventID, newDate.Format("2006-01-02"))
+			}
+			ev.URL = shift2bikesEventURL(dateID)
+			fmt.Printf("New Shift2Bikes URL: %s\n", ev.URL)
+		}
+	}
+
+	if err := doc.Commit(); err != nil {
+		return err
+	}
+	if !dryRun() {
+		fmt.Printf("Updated %q in %s\n", after.Title, eventsFile)
+	}
+	return nil
+}
+
+// resolveEditValue prompts for a field with its current value as the default.
+// Unset non-interactively keeps the current value; "-" clears it.
+func resolveEditValue(envVar, prompt, current string) (string, error) {
+	v := os.Getenv(envVar)
+	switch {
+	case v == "-":
+		return "", nil
+	case v != "":
+		return v, nil
+	case !isInteractive():
+		return current, nil
+	case current == "":
+		return resolveOptional(envVar, prompt)
+	}
+	v, err := resolveValue(envVar, prompt+` ("-" to clear)`, current)
+	if v == "-" {
+		v = ""
+	}
+	return v, err
+}
+
+// pushEventEdit applies a date move and start address change to the
+// Shift2Bikes event and returns it as updated.
+func pushEventEdit(secret shiftSecret, before, after eventEntry) (*shift2bikesEvent, error) {
+	client := newShift2BikesClient()
+	event, err := client.Retrieve(secret.EventID, secret.Secret)
+	if err != nil {
+		return nil, err
+	}
+	payload := event.shift2bikesPayload
+	payload.ID, payload.Secret = secret.EventID, secret.Secret
+
+	if after.StartAddress != before.StartAddress && after.StartAddress != "" {
+		payload.Address = after.StartAddress
+	}
+	if after.Date != before.Date {
+		oldDate, err := time.Parse(eventDateLayout, before.Date)
+		if err != nil {
+			return nil, err
+		}
+		newDate, err := time.Parse(eventDateLayout, after.Date)
+		if err != nil {
+			return nil, err
+		}
+		moved := false
+		for i := range payload.DateStatuses {
+			if payload.DateStatuses[i].Date == oldDate.Format("2006-01-02") {
+				payload.DateStatuses[i] = dateStatus{Date: newDate.Format("2006-01-02"), Status: shiftStatusActive}
+				moved = true
+			}
+		}
+		if !moved {
+			return nil, fmt.Errorf("event %s has no date %s", secret.EventID, oldDate.Format("2006-01-02"))
+		}
+	}
+
+	updated, err := client.Update(&payload)
+	if err != nil {
+		return nil, err
+	}
+	// A moved date gets a new per-date id
+	updated.Secret = secret.Secret
+	if err := saveShiftSecret(updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// dateInputFormat turns an events.md date into the MM/DD/YYYY form the date
+// prompts accept, leaving unparseable dates as they are.
+func dateInputFormat(display string) string {
+	t, err := time.Parse(eventDateLayout, display)
+	if err != nil {
+		return display
+	}
+	return t.Format("01/02/2006")
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
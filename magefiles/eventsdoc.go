@@ -0,0 +1,563 @@
+//go:build mage
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const eventsFile = "content/events.md"
+
+// eventsDoc is an editable model of events.md.
+//
+// The front matter is parsed with a real YAML parser so any valid event
+// mapping is understood, but the original lines are kept alongside the parsed
+// values. Untouched events are written back byte-for-byte, changed events only
+// have their changed fields rewritten, and new events are rendered in the
+// file's own indentation. Comments (including commented-out "#route:" lines)
+// and ordering therefore survive a load/save round trip.
+type eventsDoc struct {
+	path        string
+	original    string
+	head        []string // lines up to and including the "events:" key
+	sections    []*eventSection
+	tail        []string // lines after the last event, including the closing ---
+	dashIndent  int      // column of the "-" that starts each event
+	fieldIndent int      // column of each event's keys
+}
+
+// eventSection is a run of events introduced by a "# Comment" line at the
+// events list's indentation, e.g. "# Beaverton Bike Happy Hours".
+type eventSection struct {
+	Comment string // trimmed comment line; empty for events before the first comment
+	Line    int    // 1-based line of the comment in the file
+	lead    []string
+	header  []string
+	Events  []*docEvent
+}
+
+// docEvent is a single event in events.md.
+type docEvent struct {
+	eventEntry
+	Line       int            // 1-based line of the event's "-" in the file; 0 for added events
+	FieldLines map[string]int // 1-based line of each key present in the file
+	lead       []string
+	raw        []string
+	orig       eventEntry
+	flow       bool
+}
+
+// eventFieldOrder is the order fields are written in, matching the
+// hand-written entries in events.md.
+var eventFieldOrder = []string{"title", "date", "url", "route", "start", "end", "start_address", "tags", "status", "reason"}
+
+// requiredEventFields are always written for new events, even when empty.
+var requiredEventFields = map[string]bool{"title": true, "date": true, "start": true, "end": true}
+
+// fieldValue returns the YAML rendering of a field, or "" when it is unset.
+func (e eventEntry) fieldValue(key string) string {
+	var s string
+	switch key {
+	case "title":
+		s = e.Title
+	case "date":
+		s = e.Date
+	case "url":
+		s = e.URL
+	case "route":
+		s = e.Route
+	case "start":
+		s = e.Start
+	case "end":
+		s = e.End
+	case "start_address":
+		s = e.StartAddress
+	case "status":
+		s = e.Status
+	case "reason":
+		s = e.Reason
+	case "tags":
+		if len(e.Tags) == 0 {
+			return ""
+		}
+		return "[" + strings.Join(e.Tags, ", ") + "]"
+	}
+	if s == "" {
+		return ""
+	}
+	return yamlQuote(s)
+}
+
+// yamlQuote renders s as a double-quoted YAML scalar, escaped by YAML's rules
+// rather than Go's so it reads back as the same string.
+func yamlQuote(s string) string {
+	// A lone scalar node always encodes
+	out, _ := yaml.Marshal(&yaml.Node{Kind: yaml.ScalarNode, Style: yaml.DoubleQuotedStyle, Value: s})
+	return strings.TrimSuffix(string(out), "\n")
+}
+
+func loadEventsDoc(path string) (*eventsDoc, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseEventsDoc(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	doc.path = path
+	doc.original = string(content)
+	return doc, nil
+}
+
+func parseEventsDoc(content string) (*eventsDoc, error) {
+	lines := strings.Split(content, "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return nil, fmt.Errorf("missing opening --- front matter delimiter")
+	}
+	closeIdx := -1
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "---" {
+			closeIdx = i
+			break
+		}
+	}
+	if closeIdx == -1 {
+		return nil, fmt.Errorf("missing closing --- front matter delimiter")
+	}
+
+	// Front matter line N (1-based, as reported by yaml) is lines[N].
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(strings.Join(lines[1:closeIdx], "\n")), &root); err != nil {
+		return nil, fmt.Errorf("invalid front matter: %w", err)
+	}
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("front matter is not a YAML mapping")
+	}
+
+	var keyNode, seqNode *yaml.Node
+	fm := root.Content[0]
+	for i := 0; i+1 < len(fm.Content); i += 2 {
+		if fm.Content[i].Value == "events" {
+			keyNode, seqNode = fm.Content[i], fm.Content[i+1]
+			break
+		}
+	}
+	if keyNode == nil {
+		return nil, fmt.Errorf("no events list in front matter")
+	}
+
+	doc := &eventsDoc{dashIndent: 2, fieldIndent: 4}
+	var items []*yaml.Node
+	switch {
+	case seqNode.Kind == yaml.SequenceNode && seqNode.Style&yaml.FlowStyle != 0:
+		return nil, fmt.Errorf("line %d: flow-style events list is not supported; use one \"- title:\" block per event", seqNode.Line)
+	case seqNode.Kind == yaml.SequenceNode:
+		items = seqNode.Content
+		doc.dashIndent = seqNode.Column - 1
+		doc.fieldIndent = doc.dashIndent + 2
+		for _, item := range items {
+			if item.Kind == yaml.MappingNode && item.Style&yaml.FlowStyle == 0 {
+				doc.fieldIndent = item.Column - 1
+				break
+			}
+		}
+	case seqNode.Tag == "!!null":
+		// "events:" with nothing under it yet
+	default:
+		return nil, fmt.Errorf("line %d: events must be a list", seqNode.Line)
+	}
+
+	doc.head = append([]string(nil), lines[:keyNode.Line+1]...)
+	doc.sections = []*eventSection{{}}
+	current := doc.sections[0]
+
+	var pending []string
+	i := keyNode.Line + 1
+	for i < closeIdx {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+
+		if trimmed == "" {
+			pending = append(pending, line)
+			i++
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			if indent == doc.dashIndent {
+				current = &eventSection{Comment: trimmed, Line: i + 1, lead: pending, header: []string{line}}
+				doc.sections = append(doc.sections, current)
+				pending = nil
+			} else {
+				pending = append(pending, line)
+			}
+			i++
+			continue
+		}
+		if indent != doc.dashIndent || !strings.HasPrefix(trimmed, "-") {
+			break
+		}
+
+		// An event runs until the next line at or left of the dash column.
+		end := i + 1
+		for end < closeIdx {
+			l := lines[end]
+			if strings.TrimSpace(l) != "" && len(l)-len(strings.TrimLeft(l, " ")) <= doc.dashIndent {
+				break
+			}
+			end++
+		}
+		for end > i+1 && strings.TrimSpace(lines[end-1]) == "" {
+			end--
+		}
+
+		n := doc.eventCount()
+		if n >= len(items) {
+			return nil, fmt.Errorf("line %d: unexpected list item in events", i+1)
+		}
+		item := items[n]
+		if item.Line < i || item.Line > end-1 {
+			return nil, fmt.Errorf("line %d: could not match event to parsed YAML", i+1)
+		}
+		ev := &docEvent{
+			Line:       i + 1,
+			FieldLines: make(map[string]int),
+			lead:       pending,
+			raw:        append([]string(nil), lines[i:end]...),
+			flow:       item.Style&yaml.FlowStyle != 0,
+		}
+		if err := item.Decode(&ev.eventEntry); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		for k := 0; k+1 < len(item.Content); k += 2 {
+			ev.FieldLines[item.Content[k].Value] = item.Content[k].Line + 1
+		}
+		ev.orig = ev.eventEntry
+		ev.orig.Tags = append([]string(nil), ev.Tags...)
+		current.Events = append(current.Events, ev)
+		pending = nil
+		i = end
+	}
+	if n := doc.eventCount(); n != len(items) {
+		return nil, fmt.Errorf("found %d events but YAML has %d; check the events list indentation", n, len(items))
+	}
+
+	doc.tail = append(pending, lines[i:]...)
+	return doc, nil
+}
+
+func (d *eventsDoc) eventCount() int {
+	n := 0
+	for _, s := range d.sections {
+		n += len(s.Events)
+	}
+	return n
+}
+
+// Events returns every event in file order.
+func (d *eventsDoc) Events() []*docEvent {
+	var events []*docEvent
+	for _, s := range d.sections {
+		events = append(events, s.Events...)
+	}
+	return events
+}
+
+// FindEvent returns the first event with the given title and date, or nil.
+func (d *eventsDoc) FindEvent(title, date string) *docEvent {
+	for _, ev := range d.Events() {
+		if ev.Title == title && ev.Date == date {
+			return ev
+		}
+	}
+	return nil
+}
+
+// MatchEvents finds events by a user-supplied selector: a Shift2Bikes or
+// other event URL, an explicit date (M/D in any year, M/D/YYYY, YYYY-MM-DD or
+// the events.md format), or a title. Titles match exactly (ignoring case)
+// before falling back to substrings. Other dates parseDate accepts, like
+// "monday" or "march 9", are tried only when no title matches.
+func (d *eventsDoc) MatchEvents(sel string) []*docEvent {
+	sel = strings.TrimSpace(sel)
+	var matches []*docEvent
+	match := func(f func(ev *docEvent) bool) []*docEvent {
+		for _, ev := range d.Events() {
+			if f(ev) {
+				matches = append(matches, ev)
+			}
+		}
+		return matches
+	}
+	matchDate := func(pd parsedDate) []*docEvent {
+		return match(func(ev *docEvent) bool { return ev.Date == pd.display })
+	}
+
+	if strings.HasPrefix(sel, "http://") || strings.HasPrefix(sel, "https://") {
+		return match(func(ev *docEvent) bool { return ev.URL != "" && sameEventURL(ev.URL, sel) })
+	}
+	if t, err := time.Parse(eventDateLayout, sel); err == nil {
+		return matchDate(newParsedDate(t))
+	}
+	// M/D matches that day in any year; parseDate would pick the next one
+	if m := dateRegexShort.FindStringSubmatch(sel); m != nil {
+		return match(func(ev *docEvent) bool {
+			t, err := time.Parse(eventDateLayout, ev.Date)
+			return err == nil && int(t.Month()) == atoi(m[1]) && t.Day() == atoi(m[2])
+		})
+	}
+	if dateRegexFull.MatchString(sel) || dateRegexISO.MatchString(sel) {
+		pd, err := parseDate(sel, time.Now())
+		if err != nil {
+			return nil
+		}
+		return matchDate(pd)
+	}
+	if m := match(func(ev *docEvent) bool { return strings.EqualFold(ev.Title, sel) }); len(m) > 0 {
+		return m
+	}
+	lower := strings.ToLower(sel)
+	if m := match(func(ev *docEvent) bool { return strings.Contains(strings.ToLower(ev.Title), lower) }); len(m) > 0 {
+		return m
+	}
+	if pd, err := parseDate(sel, time.Now()); err == nil {
+		return matchDate(pd)
+	}
+	return nil
+}
+
+// Section returns the first section whose comment contains sectionComment.
+func (d *eventsDoc) Section(sectionComment string) *eventSection {
+	for _, s := range d.sections {
+		if s.Comment != "" && strings.Contains(s.Comment, sectionComment) {
+			return s
+		}
+	}
+	return nil
+}
+
+// SectionOf returns the section containing ev.
+func (d *eventsDoc) SectionOf(ev *docEvent) *eventSection {
+	for _, s := range d.sections {
+		for _, e := range s.Events {
+			if e == ev {
+				return s
+			}
+		}
+	}
+	return nil
+}
+
+// Insert adds an event at the end of the section whose comment contains
+// sectionComment, or at the end of the list when sectionComment is empty.
+func (d *eventsDoc) Insert(entry eventEntry, sectionComment string) (*docEvent, error) {
+	section := d.sections[len(d.sections)-1]
+	if sectionComment != "" {
+		section = d.Section(sectionComment)
+		if section == nil {
+			return nil, fmt.Errorf("section comment %q not found in %s", sectionComment, d.path)
+		}
+	}
+	ev := &docEvent{eventEntry: entry}
+	if len(section.Events) > 0 || (len(section.header) == 0 && d.eventCount() > 0) {
+		ev.lead = []string{""}
+	}
+	// Keep a blank line between a previously empty section and the next one
+	if len(section.Events) == 0 {
+		for i, s := range d.sections[:len(d.sections)-1] {
+			if s == section && len(d.sections[i+1].lead) == 0 {
+				d.sections[i+1].lead = []string{""}
+			}
+		}
+	}
+	section.Events = append(section.Events, ev)
+	return ev, nil
+}
+
+// InsertAfter adds an event directly after another one in the same section.
+func (d *eventsDoc) InsertAfter(after *docEvent, entry eventEntry) *docEvent {
+	ev := &docEvent{eventEntry: entry, lead: []string{""}}
+	section := d.SectionOf(after)
+	for i, e := range section.Events {
+		if e == after {
+			section.Events = append(section.Events[:i+1], append([]*docEvent{ev}, section.Events[i+1:]...)...)
+			break
+		}
+	}
+	return ev
+}
+
+// Bytes renders the document.
+func (d *eventsDoc) Bytes() []byte {
+	var out []string
+	out = append(out, d.head...)
+	for _, s := range d.sections {
+		out = append(out, s.lead...)
+		out = append(out, s.header...)
+		for _, ev := range s.Events {
+			out = append(out, ev.lead...)
+			out = append(out, d.eventLines(ev)...)
+		}
+	}
+	out = append(out, d.tail...)
+	return []byte(strings.Join(out, "\n"))
+}
+
+// Save writes the document back to the file it was loaded from.
+func (d *eventsDoc) Save() error {
+	return os.WriteFile(d.path, d.Bytes(), 0644)
+}
+
+// Commit saves the document, or with DRY_RUN set prints the unified diff
+// that saving would produce and leaves the file alone.
+func (d *eventsDoc) Commit() error {
+	if !dryRun() {
+		if err := d.Save(); err != nil {
+			return fmt.Errorf("failed to update %s: %w", d.path, err)
+		}
+		return nil
+	}
+	if diff := unifiedDiff(d.path, d.original, string(d.Bytes())); diff != "" {
+		fmt.Printf("\nDRY_RUN: not writing %s. Changes:\n\n%s", d.path, diff)
+	} else {
+		fmt.Printf("\nDRY_RUN: no changes to %s\n", d.path)
+	}
+	return nil
+}
+
+// dryRun reports whether DRY_RUN is set to a true value.
+func dryRun() bool {
+	return envTrue("DRY_RUN")
+}
+
+func (ev *docEvent) changed() bool {
+	for _, key := range eventFieldOrder {
+		if ev.eventEntry.fieldValue(key) != ev.orig.fieldValue(key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *eventsDoc) eventLines(ev *docEvent) []string {
+	switch {
+	case ev.raw == nil || ev.flow && ev.changed():
+		return formatEventYAML(ev.eventEntry, d.dashIndent, d.fieldIndent)
+	case !ev.changed():
+		return ev.raw
+	default:
+		return d.patchEvent(ev)
+	}
+}
+
+// formatEventYAML renders a new event with its "-" at dashIndent and its keys
+// at fieldIndent.
+func formatEventYAML(entry eventEntry, dashIndent, fieldIndent int) []string {
+	var lines []string
+	for _, key := range eventFieldOrder {
+		val := entry.fieldValue(key)
+		if val == "" {
+			if !requiredEventFields[key] {
+				continue
+			}
+			val = `""`
+		}
+		prefix := strings.Repeat(" ", fieldIndent)
+		if len(lines) == 0 {
+			prefix = strings.Repeat(" ", dashIndent) + "-" + strings.Repeat(" ", fieldIndent-dashIndent-1)
+		}
+		lines = append(lines, prefix+key+": "+val)
+	}
+	return lines
+}
+
+// patchEvent rewrites only the fields of ev that changed since it was loaded,
+// leaving every other line of the original entry untouched. A newly set field
+// replaces a commented-out "#key:" line if there is one.
+func (d *eventsDoc) patchEvent(ev *docEvent) []string {
+	lines := append([]string(nil), ev.raw...)
+	pad := strings.Repeat(" ", d.fieldIndent)
+
+	for k, key := range eventFieldOrder {
+		val := ev.eventEntry.fieldValue(key)
+		if val == ev.orig.fieldValue(key) {
+			continue
+		}
+
+		start, end := d.findField(lines, key)
+		switch {
+		case start >= 0 && val == "":
+			if start > 0 {
+				lines = append(lines[:start], lines[end:]...)
+			}
+		case start >= 0:
+			prefix := pad
+			if start == 0 {
+				prefix = lines[0][:d.fieldIndent]
+			}
+			lines = append(lines[:start], append([]string{prefix + key + ": " + val}, lines[end:]...)...)
+		case val != "":
+			newLine := pad + key + ": " + val
+			if c := d.findCommentedField(lines, key); c >= 0 {
+				lines[c] = newLine
+				continue
+			}
+			at := 1
+			for j := k - 1; j >= 0; j-- {
+				if _, e := d.findField(lines, eventFieldOrder[j]); e >= 0 {
+					at = e
+					break
+				}
+			}
+			lines = append(lines[:at], append([]string{newLine}, lines[at:]...)...)
+		}
+	}
+	return lines
+}
+
+// findField returns the line range [start, end) holding key in an event's
+// lines, or -1, -1 when the key is absent.
+func (d *eventsDoc) findField(lines []string, key string) (int, int) {
+	for i, l := range lines {
+		if len(l) <= d.fieldIndent || !strings.HasPrefix(l[d.fieldIndent:], key+":") {
+			continue
+		}
+		if i > 0 && strings.TrimSpace(l[:d.fieldIndent]) != "" {
+			continue
+		}
+		end := i + 1
+		for end < len(lines) {
+			next := lines[end]
+			trimmed := strings.TrimSpace(next)
+			indent := len(next) - len(strings.TrimLeft(next, " "))
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+				break
+			}
+			if indent < d.fieldIndent || indent == d.fieldIndent && !strings.HasPrefix(trimmed, "- ") {
+				break
+			}
+			end++
+		}
+		return i, end
+	}
+	return -1, -1
+}
+
+func (d *eventsDoc) findCommentedField(lines []string, key string) int {
+	for i, l := range lines {
+		trimmed := strings.TrimSpace(l)
+		if !strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.HasPrefix(strings.TrimSpace(strings.TrimPrefix(trimmed, "#")), key+":") {
+			return i
+		}
+	}
+	return -1
+}
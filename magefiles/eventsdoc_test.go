@@ -0,0 +1,191 @@
+//go:build mage
+
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// testEventsDoc has the quirks of the real file: sections, a commented-out
+// route, a flow-style entry, comments between events and content after the
+// front matter.
+const testEventsDoc = `---
+title: "Events"
+events:
+  # Beaverton Bike Happy Hours
+  - title: "1/12 Bike Happy Hour"
+    date: "January 12, 2026"
+    url: "https://shift2bikes.org/calendar/event-23092"
+    start: "Beaverton"
+    end: "Beaverton"
+    start_address: "4250 SW Rose Biggi Ave, Beaverton, OR"
+    tags: [happy-hour]
+
+  - title: "1/12 Post-Bike Happy Hour Ride"
+    date: "January 12, 2026"
+    #route: "https://ridewithgps.com/routes/12345"
+    start: "Beaverton"
+    end: "Beaverton"
+    tags: [ride]
+
+  # Tigard Happy Hours
+
+  # Special Rides
+  # (kept in date order)
+  - {title: "5/2 Quatama Ride", date: "May 2, 2026", start: "Quatama", end: "Quatama"}
+  - title: 'Single quoted'
+    date: "May 9, 2026"
+    start: Beaverton   # unquoted, with a comment
+    end: "Beaverton"
+---
+
+Body text.
+`
+
+func mustParseEventsDoc(t *testing.T, content string) *eventsDoc {
+	t.Helper()
+	doc, err := parseEventsDoc(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestEventsDocRoundTrip(t *testing.T) {
+	real, err := os.ReadFile("../" + eventsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"fixture": testEventsDoc, eventsFile: string(real)} {
+		t.Run(name, func(t *testing.T) {
+			doc := mustParseEventsDoc(t, content)
+			if got := string(doc.Bytes()); got != content {
+				t.Errorf("round trip changed the document:\n%s", unifiedDiff(name, content, got))
+			}
+		})
+	}
+}
+
+func TestEventsDocPatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		edit  func(ev *docEvent)
+		old   string
+		new   string
+	}{
+		{
+			name:  "change one field",
+			title: "1/12 Bike Happy Hour",
+			edit:  func(ev *docEvent) { ev.URL = "https://shift2bikes.org/calendar/event-30001" },
+			old:   `    url: "https://shift2bikes.org/calendar/event-23092"`,
+			new:   `    url: "https://shift2bikes.org/calendar/event-30001"`,
+		},
+		{
+			name:  "replace commented-out route",
+			title: "1/12 Post-Bike Happy Hour Ride",
+			edit:  func(ev *docEvent) { ev.Route = "https://ridewithgps.com/routes/999" },
+			old:   `    #route: "https://ridewithgps.com/routes/12345"`,
+			new:   `    route: "https://ridewithgps.com/routes/999"`,
+		},
+		{
+			name:  "keep an unchanged field's formatting",
+			title: "Single quoted",
+			edit:  func(ev *docEvent) { ev.Date = "May 10, 2026" },
+			old:   `    date: "May 9, 2026"`,
+			new:   `    date: "May 10, 2026"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := mustParseEventsDoc(t, testEventsDoc)
+			var ev *docEvent
+			for _, e := range doc.Events() {
+				if e.Title == tt.title {
+					ev = e
+				}
+			}
+			if ev == nil {
+				t.Fatalf("no event %q", tt.title)
+			}
+			tt.edit(ev)
+
+			want := strings.Replace(testEventsDoc, tt.old+"\n", tt.new+"\n", 1)
+			if got := string(doc.Bytes()); got != want {
+				t.Errorf("patched document differs:\n%s", unifiedDiff("want", want, got))
+			}
+		})
+	}
+}
+
+func TestEventsDocInsertIntoEmptySection(t *testing.T) {
+	doc := mustParseEventsDoc(t, testEventsDoc)
+	_, err := doc.Insert(eventEntry{
+		Title: "1/6 Tigard Happy Hour",
+		Date:  "January 6, 2026",
+		Start: "Tigard",
+		End:   "Tigard",
+		Tags:  []string{"happy-hour"},
+	}, "# Tigard Happy Hours")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Replace(testEventsDoc, "  # Tigard Happy Hours\n\n", `  # Tigard Happy Hours
+  - title: "1/6 Tigard Happy Hour"
+    date: "January 6, 2026"
+    start: "Tigard"
+    end: "Tigard"
+    tags: [happy-hour]
+
+`, 1)
+	if got := string(doc.Bytes()); got != want {
+		t.Errorf("inserted document differs:\n%s", unifiedDiff("want", want, got))
+	}
+
+	if _, err := doc.Insert(eventEntry{Title: "x"}, "# No Such Section"); err == nil {
+		t.Error("Insert into a missing section succeeded, want an error")
+	}
+}
+
+func TestEventsDocInsertAfter(t *testing.T) {
+	doc := mustParseEventsDoc(t, testEventsDoc)
+	prev := doc.FindEvent("1/12 Bike Happy Hour", "January 12, 2026")
+	doc.InsertAfter(prev, eventEntry{Title: "1/12 Extra", Date: "January 12, 2026", Start: "Beaverton", End: "Beaverton"})
+
+	reparsed := mustParseEventsDoc(t, string(doc.Bytes()))
+	var titles []string
+	for _, ev := range reparsed.Events() {
+		titles = append(titles, ev.Title)
+	}
+	if titles[0] != "1/12 Bike Happy Hour" || titles[1] != "1/12 Extra" || titles[2] != "1/12 Post-Bike Happy Hour Ride" {
+		t.Errorf("events after InsertAfter = %q", titles)
+	}
+}
+
+func TestEventsDocRejectsFlowList(t *testing.T) {
+	_, err := parseEventsDoc("---\nevents: [{title: \"a\", date: \"May 2, 2026\"}]\n---\n")
+	if err == nil || !strings.Contains(err.Error(), "flow-style") {
+		t.Errorf("parse of a flow-style events list = %v, want a flow-style error", err)
+	}
+}
+
+func TestEventsDocQuoting(t *testing.T) {
+	titles := []string{
+		`5/2 Ride é \x41`,
+		"5/2 Bike 🚲 Ride",
+		`5/2 "Quoted" \ ride`,
+		"5/2 Tab\tand é",
+	}
+	for _, title := range titles {
+		doc := mustParseEventsDoc(t, testEventsDoc)
+		doc.FindEvent("1/12 Bike Happy Hour", "January 12, 2026").Title = title
+
+		reparsed := mustParseEventsDoc(t, string(doc.Bytes()))
+		if got := reparsed.Events()[0].Title; got != title {
+			t.Errorf("title %q read back as %q", title, got)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+//go:build mage
+
+package main
+
+import "time"
+
+// federalHolidayNames maps the ids used in data/recurring.yaml to display names.
+var federalHolidayNames = map[string]string{
+	"new-years-day":    "New Year's Day",
+	"mlk-day":          "Martin Luther King Jr. Day",
+	"presidents-day":   "Presidents' Day",
+	"memorial-day":     "Memorial Day",
+	"juneteenth":       "Juneteenth",
+	"independence-day": "Independence Day",
+	"labor-day":        "Labor Day",
+	"columbus-day":     "Columbus Day / Indigenous Peoples' Day",
+	"veterans-day":     "Veterans Day",
+	"thanksgiving":     "Thanksgiving Day",
+	"christmas-day":    "Christmas Day",
+}
+
+// usFederalHolidays returns the observed US federal holidays in a year, keyed
+// by YYYY-MM-DD with the holiday id as value. Fixed-date holidays falling on a
+// Saturday are observed the Friday before, on a Sunday the Monday after.
+func usFederalHolidays(year int) map[string]string {
+	holidays := make(map[string]string)
+	add := func(id string, d time.Time) {
+		holidays[d.Format("2006-01-02")] = id
+	}
+	fixed := func(id string, month time.Month, day int) {
+		d := time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+		switch d.Weekday() {
+		case time.Saturday:
+			d = d.AddDate(0, 0, -1)
+		case time.Sunday:
+			d = d.AddDate(0, 0, 1)
+		}
+		add(id, d)
+	}
+
+	fixed("new-years-day", time.January, 1)
+	add("mlk-day", nthWeekday(year, time.January, time.Monday, 3))
+	add("presidents-day", nthWeekday(year, time.February, time.Monday, 3))
+	add("memorial-day", lastWeekday(year, time.May, time.Monday))
+	fixed("juneteenth", time.June, 19)
+	fixed("independence-day", time.July, 4)
+	add("labor-day", nthWeekday(year, time.September, time.Monday, 1))
+	add("columbus-day", nthWeekday(year, time.October, time.Monday, 2))
+	fixed("veterans-day", time.November, 11)
+	add("thanksgiving", nthWeekday(year, time.November, time.Thursday, 4))
+	fixed("christmas-day", time.December, 25)
+
+	// New Year's Day of the following year can be observed on December 31
+	if next := time.Date(year+1, time.January, 1, 0, 0, 0, 0, time.Local); next.Weekday() == time.Saturday {
+		add("new-years-day", next.AddDate(0, 0, -1))
+	}
+	return holidays
+}
+
+// nextWeekday returns the first Monday-Friday day after d.
+func nextWeekday(d time.Time) time.Time {
+	d = d.AddDate(0, 0, 1)
+	for d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+		d = d.AddDate(0, 0, 1)
+	}
+	return d
+}
@@ -0,0 +1,264 @@
+//go:build mage
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const shiftImportFile = "data/shift2bikes-import.yaml"
+
+// shiftImportConfig is data/shift2bikes-import.yaml.
+type shiftImportConfig struct {
+	Organizers []string          `yaml:"organizers"`
+	Section    string            `yaml:"section"`
+	Tags       []string          `yaml:"tags"`
+	Rules      []shiftImportRule `yaml:"rules"`
+	Exclude    []string          `yaml:"exclude"`
+}
+
+type shiftImportRule struct {
+	Organizer string   `yaml:"organizer"`
+	Area      string   `yaml:"area"`
+	Keywords  []string `yaml:"keywords"`
+}
+
+func (r shiftImportRule) matches(o shift2bikesOccurrence) bool {
+	if r.Organizer == "" && r.Area == "" && len(r.Keywords) == 0 {
+		return false
+	}
+	if r.Organizer != "" && !containsFold(o.Organizer, r.Organizer) {
+		return false
+	}
+	if r.Area != "" && !strings.EqualFold(o.Area, r.Area) {
+		return false
+	}
+	if len(r.Keywords) > 0 {
+		found := false
+		for _, k := range r.Keywords {
+			if containsFold(o.Title, k) || containsFold(o.Details, k) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *shiftImportConfig) matches(o shift2bikesOccurrence) bool {
+	for _, x := range c.Exclude {
+		if containsFold(o.Title, x) {
+			return false
+		}
+	}
+	for _, r := range c.Rules {
+		if r.matches(o) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *shiftImportConfig) ownOrganizer(name string) bool {
+	for _, o := range c.Organizers {
+		if containsFold(name, o) {
+			return true
+		}
+	}
+	return false
+}
+
+func loadShiftImportConfig(path string) (*shiftImportConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg shiftImportConfig
+	if err := yaml.Unmarshal(content, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(cfg.Rules) == 0 {
+		return nil, fmt.Errorf("%s: no rules", path)
+	}
+	for _, t := range cfg.Tags {
+		if _, err := parseTags(t); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return &cfg, nil
+}
+
+// ImportShift2Bikes copies rides from the Shift2Bikes calendar into
+// events.md. Events are picked by the rules in data/shift2bikes-import.yaml;
+// ones already linked from events.md are skipped.
+//
+// Events whose venue or end isn't one of the known locations are skipped with
+// a warning; set IMPORT_FORCE=1 to import them anyway and fix them by hand.
+//
+// Set DRY_RUN=1 to list the matches and print the diff without writing.
+//
+// Usage: mage importShift2Bikes 2026-06-01 2026-08-31
+func ImportShift2Bikes(from, to string) error {
+	start, err := time.ParseInLocation("2006-01-02", from, time.Local)
+	if err != nil {
+		return fmt.Errorf("invalid start date %q; use YYYY-MM-DD", from)
+	}
+	end, err := time.ParseInLocation("2006-01-02", to, time.Local)
+	if err != nil {
+		return fmt.Errorf("invalid end date %q; use YYYY-MM-DD", to)
+	}
+	if end.Before(start) {
+		return fmt.Errorf("end date %s is before start date %s", to, from)
+	}
+
+	cfg, err := loadShiftImportConfig(shiftImportFile)
+	if err != nil {
+		return err
+	}
+	doc, err := loadEventsDoc(eventsFile)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Fetching Shift2Bikes events from %s to %s...\n", from, to)
+	occurrences, err := newShift2BikesClient().Range(start, end)
+	if err != nil {
+		return fmt.Errorf("Shift2Bikes API error: %w", err)
+	}
+
+	linked := make(map[string]bool)
+	for _, ev := range doc.Events() {
+		if m := shift2bikesEventRegex.FindStringSubmatch(ev.URL); m != nil {
+			linked[m[1]] = true
+		}
+	}
+
+	force := envTrue("IMPORT_FORCE")
+	added, existing, skipped, warnings := 0, 0, 0, 0
+	for _, o := range occurrences {
+		if o.Cancelled || !cfg.matches(o) {
+			continue
+		}
+		if linked[o.CaldailyID] {
+			existing++
+			continue
+		}
+		entry, warns, err := shiftImportEntry(cfg, o, force)
+		if err != nil {
+			fmt.Printf("  ! %s (%s) skipped: %v\n", o.Title, o.Date, err)
+			skipped++
+			continue
+		}
+		if _, err := doc.Insert(entry, cfg.Section); err != nil {
+			return err
+		}
+		linked[o.CaldailyID] = true
+		fmt.Printf("  + %s (%s) %s\n", entry.Title, entry.Date, entry.URL)
+		for _, w := range warns {
+			fmt.Printf("      ! %s\n", w)
+		}
+		warnings += len(warns)
+		added++
+	}
+
+	if added > 0 {
+		if err := doc.Commit(); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("\nImported %d events, %d already in %s, skipped %d, %d warnings.\n", added, existing, eventsFile, skipped, warnings)
+	if skipped > 0 && !force {
+		fmt.Println("Add missing locations to eventLocations, or set IMPORT_FORCE=1 to import those events anyway.")
+	}
+	if warnings > 0 {
+		fmt.Println("Fix the warnings above (mage validateEvents) before merging.")
+	}
+	return nil
+}
+
+// shiftImportEntry maps a calendar entry to an events.md entry. Start and end
+// are matched against eventLocations using the venue/address and locend. A
+// location that can't be matched is an error, unless force is set, in which
+// case the raw text is used and returned as a warning.
+func shiftImportEntry(cfg *shiftImportConfig, o shift2bikesOccurrence, force bool) (eventEntry, []string, error) {
+	t, err := time.ParseInLocation("2006-01-02", o.Date, time.Local)
+	if err != nil {
+		return eventEntry{}, nil, fmt.Errorf("invalid date %q", o.Date)
+	}
+	d := newParsedDate(t)
+
+	title := strings.TrimSpace(o.Title)
+	if !titleDateRegex.MatchString(title) {
+		title = d.short + " " + title
+	}
+
+	var warns []string
+	unknown := func(field, text string) error {
+		err := fmt.Errorf("%s %q is not a known location", field, text)
+		if !force {
+			return err
+		}
+		warns = append(warns, err.Error())
+		return nil
+	}
+	start := matchLocation(o.Venue, o.Address)
+	if start == "" {
+		if err := unknown("start", o.Venue); err != nil {
+			return eventEntry{}, nil, err
+		}
+		start = o.Venue
+	}
+	end := start
+	if o.LocEnd != "" {
+		if end = matchLocation(o.LocEnd); end == "" {
+			if err := unknown("end", o.LocEnd); err != nil {
+				return eventEntry{}, nil, err
+			}
+			end = o.LocEnd
+		}
+	}
+
+	tags := append([]string(nil), cfg.Tags...)
+	if !cfg.ownOrganizer(o.Organizer) {
+		tags = append(tags, "not-rws")
+	}
+
+	url := o.Shareable
+	if url == "" {
+		url = shift2bikesEventURL(o.CaldailyID)
+	}
+
+	return eventEntry{
+		Title:        title,
+		Date:         d.display,
+		URL:          url,
+		Start:        start,
+		End:          end,
+		StartAddress: strings.TrimSpace(o.Address),
+		Tags:         tags,
+	}, warns, nil
+}
+
+// matchLocation returns the first of eventLocations named in any of texts.
+func matchLocation(texts ...string) string {
+	for _, text := range texts {
+		for _, l := range eventLocations {
+			if containsFold(text, l) {
+				return l
+			}
+		}
+	}
+	return ""
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
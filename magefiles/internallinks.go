@@ -0,0 +1,118 @@
+//go:build mage
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var baseURLRegex = regexp.MustCompile(`(?m)^baseURL\s*=\s*['"]([^'"]+)['"]`)
+
+// checkInternalLinks resolves every relative and same-site href, src, srcset
+// and aria-controls in the site against the files in public/, and fragments
+// against the ids of the target page. It returns one result per target with
+// every place it is referenced.
+func checkInternalLinks(idx *siteIndex) []linkResult {
+	byTarget := make(map[string]*linkResult)
+	var order []string
+	for _, page := range idx.sortedPages() {
+		pageURL := idx.site.ResolveReference(&url.URL{Path: strings.TrimPrefix(page.path, "/")})
+		for _, l := range page.links {
+			status, target := idx.checkInternalRef(pageURL, l.URL)
+			if target == "" {
+				continue
+			}
+			r := byTarget[target]
+			if r == nil {
+				r = &linkResult{URL: target, Internal: true, Status: status}
+				byTarget[target] = r
+				order = append(order, target)
+			}
+			r.Sources = append(r.Sources, l)
+		}
+	}
+
+	sort.Strings(order)
+	results := make([]linkResult, len(order))
+	for i, target := range order {
+		results[i] = *byTarget[target]
+	}
+	return results
+}
+
+// checkInternalRef returns the site path ref, found on pageURL, resolves to
+// and why it is broken, or "" for both when ref is not on this site.
+func (idx *siteIndex) checkInternalRef(pageURL *url.URL, ref string) (status, target string) {
+	if ref == "" || strings.HasPrefix(ref, "//") {
+		return "", ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return fmt.Sprintf("invalid URL: %v", err), ref
+	}
+	if u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https" {
+		return "", "" // mailto:, tel:, data:, javascript:
+	}
+	u = pageURL.ResolveReference(u)
+	if u.Host != idx.site.Host {
+		return "", ""
+	}
+
+	rel, ok := strings.CutPrefix(u.Path, idx.site.Path)
+	if !ok {
+		return "outside the site", u.Path
+	}
+	target = "/" + strings.TrimSuffix(rel, "index.html")
+	if u.Fragment != "" {
+		target += "#" + u.Fragment
+	}
+
+	file := rel
+	if file == "" || strings.HasSuffix(file, "/") {
+		file += "index.html"
+	}
+	if info, err := os.Stat(filepath.Join(idx.dir, filepath.FromSlash(file))); err == nil && info.IsDir() {
+		file = strings.TrimSuffix(file, "/") + "/index.html"
+	} else if err != nil {
+		return fmt.Sprintf("missing file %s", filepath.Join(idx.dir, filepath.FromSlash(file))), target
+	}
+
+	if u.Fragment == "" || u.Fragment == "top" {
+		return "", target
+	}
+	page := idx.pages[file]
+	if page == nil {
+		return "", target // not HTML; nothing to check the fragment against
+	}
+	if !page.ids[u.Fragment] {
+		return fmt.Sprintf("missing anchor #%s on /%s", u.Fragment, strings.TrimSuffix(file, "index.html")), target
+	}
+	return "", target
+}
+
+// siteBaseURL reads baseURL from hugo.toml, so absolute links to the site
+// itself are checked against public/ as well.
+func siteBaseURL() (*url.URL, error) {
+	content, err := os.ReadFile("hugo.toml")
+	if err != nil {
+		return nil, err
+	}
+	m := baseURLRegex.FindStringSubmatch(string(content))
+	if m == nil {
+		return nil, fmt.Errorf("hugo.toml has no baseURL")
+	}
+	u, err := url.Parse(m[1])
+	if err != nil {
+		return nil, fmt.Errorf("hugo.toml baseURL: %w", err)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return u, nil
+}
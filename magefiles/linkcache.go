@@ -0,0 +1,163 @@
+//go:build mage
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const defaultLinkCacheFile = ".cache/links.json"
+
+// linkCache remembers the last result for each external link between
+// CheckLinks runs, so links that were fine recently aren't fetched again.
+type linkCache struct {
+	path  string
+	ttl   time.Duration
+	force bool
+
+	mu      sync.Mutex
+	entries map[string]*linkCacheEntry
+}
+
+// linkCacheEntry is the last confirmed result for a URL. Transient failures
+// are not recorded.
+type linkCacheEntry struct {
+	Result       string    `json:"result"` // ok or dead
+	HTTPStatus   int       `json:"http_status,omitempty"`
+	Status       string    `json:"status,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Checked      time.Time `json:"checked"`
+}
+
+// loadLinkCache reads the cache at LINKS_CACHE (default .cache/links.json).
+// A missing or unreadable cache just means every link is checked.
+func loadLinkCache() (*linkCache, error) {
+	ttl, err := envDuration("LINKS_CACHE_TTL", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	c := &linkCache{
+		path:    defaultLinkCacheFile,
+		ttl:     ttl,
+		force:   envTrue("LINKS_FORCE"),
+		entries: make(map[string]*linkCacheEntry),
+	}
+	if p := os.Getenv("LINKS_CACHE"); p != "" {
+		c.path = p
+	}
+
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		fmt.Printf("Ignoring unreadable link cache %s: %v\n", c.path, err)
+		c.entries = make(map[string]*linkCacheEntry)
+	}
+	return c, nil
+}
+
+// fresh returns the cached entry for url when it was ok within the TTL.
+func (c *linkCache) fresh(url string) *linkCacheEntry {
+	if c.force {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entries[url]
+	if e == nil || e.Result != "ok" || time.Since(e.Checked) > c.ttl {
+		return nil
+	}
+	return e
+}
+
+// validators sets If-None-Match and If-Modified-Since on req from the last
+// ok response for its URL, so an unchanged page comes back as 304.
+func (c *linkCache) validators(url string, req *http.Request) {
+	if c.force {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entries[url]
+	if e == nil || e.Result != "ok" {
+		return
+	}
+	if e.ETag != "" {
+		req.Header.Set("If-None-Match", e.ETag)
+	}
+	if e.LastModified != "" {
+		req.Header.Set("If-Modified-Since", e.LastModified)
+	}
+}
+
+// Save records the external results and writes the cache, dropping links
+// that are no longer on the site.
+func (c *linkCache) Save(results []linkResult) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	entries := make(map[string]*linkCacheEntry)
+	for i := range results {
+		r := &results[i]
+		if r.Internal || r.Skipped != "" {
+			continue
+		}
+		prev := c.entries[r.URL]
+		switch {
+		case r.Transient || r.Attempts == 0:
+			// Nothing new was learned; keep what we had.
+			if prev != nil {
+				entries[r.URL] = prev
+			}
+		case r.HTTPStatus == http.StatusNotModified && prev != nil:
+			// A 304 may carry new validators; the next request must send them
+			e := *prev
+			e.Checked = now
+			if r.ETag != "" {
+				e.ETag = r.ETag
+			}
+			if r.LastModified != "" {
+				e.LastModified = r.LastModified
+			}
+			entries[r.URL] = &e
+		default:
+			e := &linkCacheEntry{
+				Result:       "ok",
+				HTTPStatus:   r.HTTPStatus,
+				Status:       r.Status,
+				ETag:         r.ETag,
+				LastModified: r.LastModified,
+				Checked:      now,
+			}
+			if r.Dead() {
+				e.Result = "dead"
+			}
+			entries[r.URL] = e
+		}
+	}
+	c.entries = entries
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write link cache: %w", err)
+	}
+	return nil
+}
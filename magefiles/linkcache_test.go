@@ -0,0 +1,117 @@
+//go:build mage
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testLinkCache loads the cache at path with the given TTL.
+func testLinkCache(t *testing.T, path, ttl string) *linkCache {
+	t.Helper()
+	t.Setenv("LINKS_CACHE", path)
+	t.Setenv("LINKS_CACHE_TTL", ttl)
+	t.Setenv("LINKS_FORCE", "")
+	cache, err := loadLinkCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cache
+}
+
+func TestLinkCache(t *testing.T) {
+	const (
+		modified1 = "Mon, 02 Nov 2026 10:00:00 GMT"
+		modified2 = "Tue, 03 Nov 2026 10:00:00 GMT"
+	)
+	var (
+		mu       sync.Mutex
+		requests []string // "If-None-Match|If-Modified-Since" of each page request
+		etag     string
+		modified string
+	)
+	// serve sets the validators the page is served with. The page itself
+	// never changes, so any conditional request gets a 304.
+	serve := func(e, m string) {
+		mu.Lock()
+		defer mu.Unlock()
+		etag, modified = e, m
+	}
+	lastRequest := func() (string, int) {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests[len(requests)-1], len(requests)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/gone" {
+			http.NotFound(w, r)
+			return
+		}
+		requests = append(requests, r.Header.Get("If-None-Match")+"|"+r.Header.Get("If-Modified-Since"))
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", modified)
+		if r.Header.Get("If-None-Match") != "" {
+			w.WriteHeader(http.StatusNotModified)
+		}
+	}))
+	defer srv.Close()
+	page, gone := srv.URL+"/page", srv.URL+"/gone"
+	cfg := linkCheckConfig{Concurrency: 2, PerHost: 2, Timeout: 5 * time.Second}
+	path := filepath.Join(t.TempDir(), "cache", "links.json")
+
+	check := func(cache *linkCache, links ...string) []linkResult {
+		t.Helper()
+		results := checkLinksParallel(cfg, cache, links)
+		if err := cache.Save(results); err != nil {
+			t.Fatal(err)
+		}
+		return results
+	}
+
+	// First run: nothing cached, the results are saved
+	serve(`"v1"`, modified1)
+	check(testLinkCache(t, path, "1h"), page, gone)
+	cache := testLinkCache(t, path, "1h")
+	if e := cache.entries[page]; e == nil || e.Result != "ok" || e.ETag != `"v1"` || e.LastModified != modified1 {
+		t.Fatalf("cached %s = %+v, want ok with the first validators", page, e)
+	}
+	if e := cache.entries[gone]; e == nil || e.Result != "dead" || e.HTTPStatus != http.StatusNotFound {
+		t.Errorf("cached %s = %+v, want dead with HTTP 404", gone, e)
+	}
+
+	// Within the TTL the page is not fetched again
+	results := check(cache, page)
+	if _, n := lastRequest(); !results[0].Cached || results[0].Attempts != 0 || n != 1 {
+		t.Errorf("fresh link: result %+v after %d requests, want cached without a request", results[0], n)
+	}
+	cache = testLinkCache(t, path, "1h")
+	if cache.entries[page] == nil {
+		t.Fatalf("fresh link dropped from the cache")
+	}
+	if cache.entries[gone] != nil {
+		t.Errorf("link no longer on the site is still cached")
+	}
+
+	// Past the TTL the page is fetched with the saved validators, and the
+	// new ones sent with the 304 replace them
+	serve(`"v2"`, modified2)
+	results = check(testLinkCache(t, path, "0s"), page)
+	if results[0].HTTPStatus != http.StatusNotModified || !results[0].Cached {
+		t.Errorf("stale link: result %+v, want a cached 304", results[0])
+	}
+	want := `"v1"|` + modified1
+	if got, _ := lastRequest(); got != want {
+		t.Errorf("conditional request sent %q, want %q", got, want)
+	}
+	e := testLinkCache(t, path, "1h").entries[page]
+	if e == nil || e.Result != "ok" || e.ETag != `"v2"` || e.LastModified != modified2 {
+		t.Errorf("cached %s after a 304 = %+v, want ok with the new validators", page, e)
+	}
+}
@@ -0,0 +1,328 @@
+//go:build mage
+
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// linkSummary counts the results of a CheckLinks run.
+type linkSummary struct {
+	Total     int `json:"total"`
+	OK        int `json:"ok"`
+	Dead      int `json:"dead"`
+	Transient int `json:"transient"`
+	Skipped   int `json:"skipped"`
+	Cached    int `json:"cached"`
+	Internal  int `json:"internal"`
+	External  int `json:"external"`
+}
+
+func summarizeLinks(results []linkResult) linkSummary {
+	s := linkSummary{Total: len(results)}
+	for i := range results {
+		r := &results[i]
+		switch {
+		case r.Dead():
+			s.Dead++
+		case r.Transient:
+			s.Transient++
+		case r.Skipped != "":
+			s.Skipped++
+		default:
+			s.OK++
+		}
+		if r.Cached {
+			s.Cached++
+		}
+		if r.Internal {
+			s.Internal++
+		} else {
+			s.External++
+		}
+	}
+	return s
+}
+
+func (r *linkResult) kind() string {
+	if r.Internal {
+		return "internal"
+	}
+	return "external"
+}
+
+func (r *linkResult) outcome() string {
+	switch {
+	case r.Dead():
+		return "dead"
+	case r.Transient:
+		return "transient"
+	case r.Skipped != "":
+		return "skipped"
+	}
+	return "ok"
+}
+
+// writeLinkReports writes the reports selected by the LINKS_REPORT_*
+// environment variables.
+func writeLinkReports(results []linkResult) error {
+	reports := []struct {
+		env   string
+		write func(string, []linkResult) error
+	}{
+		{"LINKS_REPORT_JSON", writeLinkReportJSON},
+		{"LINKS_REPORT_JUNIT", writeLinkReportJUnit},
+		{"LINKS_REPORT_MARKDOWN", writeLinkReportMarkdown},
+	}
+	for _, rep := range reports {
+		path := os.Getenv(rep.env)
+		if path == "" {
+			continue
+		}
+		if err := rep.write(path, results); err != nil {
+			return fmt.Errorf("failed to write %s report: %w", rep.env, err)
+		}
+		fmt.Printf("Wrote %s\n", path)
+	}
+	return nil
+}
+
+type jsonLinkReport struct {
+	Checked time.Time        `json:"checked"`
+	Summary linkSummary      `json:"summary"`
+	Links   []jsonLinkResult `json:"links"`
+}
+
+type jsonLinkResult struct {
+	URL        string           `json:"url"`
+	Kind       string           `json:"kind"`
+	Result     string           `json:"result"`
+	Status     string           `json:"status,omitempty"`
+	HTTPStatus int              `json:"http_status,omitempty"`
+	Attempts   int              `json:"attempts,omitempty"`
+	LatencyMS  int64            `json:"latency_ms,omitempty"`
+	Redirects  []string         `json:"redirects,omitempty"`
+	Cached     bool             `json:"cached,omitempty"`
+	Skipped    string           `json:"skipped,omitempty"`
+	Sources    []jsonLinkSource `json:"sources,omitempty"`
+	Content    []string         `json:"content,omitempty"`
+}
+
+type jsonLinkSource struct {
+	Page    string `json:"page"`
+	Line    int    `json:"line"`
+	Element string `json:"element"`
+	Attr    string `json:"attr"`
+	Context string `json:"context,omitempty"`
+}
+
+func writeLinkReportJSON(path string, results []linkResult) error {
+	report := jsonLinkReport{Checked: time.Now(), Summary: summarizeLinks(results)}
+	for i := range results {
+		r := &results[i]
+		jr := jsonLinkResult{
+			URL:        r.URL,
+			Kind:       r.kind(),
+			Result:     r.outcome(),
+			Status:     r.Status,
+			HTTPStatus: r.HTTPStatus,
+			Attempts:   r.Attempts,
+			LatencyMS:  r.Latency.Milliseconds(),
+			Redirects:  r.Redirects,
+			Cached:     r.Cached,
+			Skipped:    r.Skipped,
+			Content:    r.Content,
+		}
+		for _, src := range r.Sources {
+			js := jsonLinkSource{Page: src.Page, Line: src.Line, Element: src.element(), Attr: src.Attr}
+			if src.Context != nil && src.Context.Title != "" {
+				js.Context = fmt.Sprintf("%s %q", src.Context.Kind, src.Context.Title)
+			}
+			jr.Sources = append(jr.Sources, js)
+		}
+		report.Links = append(report.Links, jr)
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Name    string           `xml:"name,attr"`
+	Tests   int              `xml:"tests,attr"`
+	Fails   int              `xml:"failures,attr"`
+	Skips   int              `xml:"skipped,attr"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name  string          `xml:"name,attr"`
+	Tests int             `xml:"tests,attr"`
+	Fails int             `xml:"failures,attr"`
+	Skips int             `xml:"skipped,attr"`
+	Time  string          `xml:"time,attr"`
+	Cases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// writeLinkReportJUnit writes one test suite per kind of link, with a test
+// case per URL that fails when the link is dead. Transient failures are
+// reported as skipped so they don't fail the build.
+func writeLinkReportJUnit(path string, results []linkResult) error {
+	suites := junitTestSuites{Name: "checkLinks"}
+	for _, internal := range []bool{true, false} {
+		suite := junitTestSuite{Name: "external links"}
+		if internal {
+			suite.Name = "internal links"
+		}
+		var total time.Duration
+		for i := range results {
+			r := &results[i]
+			if r.Internal != internal {
+				continue
+			}
+			tc := junitTestCase{
+				Name:      r.URL,
+				Classname: "checkLinks." + r.kind(),
+				Time:      junitSeconds(r.Latency),
+			}
+			var details []string
+			for _, src := range r.Sources {
+				details = append(details, "Found on: "+src.String())
+			}
+			for _, line := range r.Content {
+				details = append(details, "Source: "+line)
+			}
+			switch {
+			case r.Dead():
+				tc.Failure = &junitFailure{Message: r.Status, Text: strings.Join(details, "\n")}
+				suite.Fails++
+			case r.Transient:
+				tc.Skipped = &junitSkipped{Message: "transient: " + r.Status}
+				tc.SystemOut = strings.Join(details, "\n")
+				suite.Skips++
+			case r.Skipped != "":
+				tc.Skipped = &junitSkipped{Message: r.Skipped}
+				suite.Skips++
+			default:
+				if len(r.Redirects) > 0 {
+					details = append(details, "Redirects: "+strings.Join(r.Redirects, " -> "))
+				}
+				tc.SystemOut = strings.Join(details, "\n")
+			}
+			total += r.Latency
+			suite.Cases = append(suite.Cases, tc)
+		}
+		suite.Tests = len(suite.Cases)
+		suite.Time = junitSeconds(total)
+		suites.Tests += suite.Tests
+		suites.Fails += suite.Fails
+		suites.Skips += suite.Skips
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte(xml.Header), append(data, '\n')...), 0644)
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// writeLinkReportMarkdown appends a summary and a table of dead links to
+// path, so it can point at $GITHUB_STEP_SUMMARY or a PR comment body.
+func writeLinkReportMarkdown(path string, results []linkResult) error {
+	s := summarizeLinks(results)
+	var b strings.Builder
+
+	b.WriteString("## Link check\n\n")
+	switch {
+	case s.Dead == 0 && s.Transient == 0:
+		fmt.Fprintf(&b, "✅ All %d links are valid", s.Total)
+	case s.Dead == 0:
+		fmt.Fprintf(&b, "✅ No dead links among %d", s.Total)
+	default:
+		fmt.Fprintf(&b, "❌ %d of %d links are dead", s.Dead, s.Total)
+	}
+	fmt.Fprintf(&b, " (%d internal, %d external, %d skipped, %d from cache).\n\n", s.Internal, s.External, s.Skipped, s.Cached)
+
+	if s.Dead > 0 {
+		writeMarkdownLinkTable(&b, results, (*linkResult).Dead)
+	}
+	if s.Transient > 0 {
+		fmt.Fprintf(&b, "⚠️ %d links could not be checked and may be temporarily down:\n\n", s.Transient)
+		writeMarkdownLinkTable(&b, results, func(r *linkResult) bool { return r.Transient })
+	}
+
+	if s.Skipped > 0 {
+		fmt.Fprintf(&b, "<details><summary>%d skipped</summary>\n\n", s.Skipped)
+		for i := range results {
+			if r := &results[i]; r.OK() && r.Skipped != "" {
+				fmt.Fprintf(&b, "- %s (%s)\n", r.URL, r.Skipped)
+			}
+		}
+		b.WriteString("\n</details>\n\n")
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeMarkdownLinkTable(b *strings.Builder, results []linkResult, include func(*linkResult) bool) {
+	b.WriteString("| Link | Status | Found on | Source |\n")
+	b.WriteString("|------|--------|----------|--------|\n")
+	for i := range results {
+		r := &results[i]
+		if !include(r) {
+			continue
+		}
+		var found []string
+		for _, src := range r.Sources {
+			found = append(found, src.String())
+		}
+		fmt.Fprintf(b, "| %s | %s | %s | %s |\n",
+			markdownCell(r.URL), markdownCell(r.Status),
+			markdownCell(strings.Join(found, "<br>")), markdownCell(strings.Join(r.Content, "<br>")))
+	}
+	b.WriteString("\n")
+}
+
+// markdownCell escapes pipes so a value stays in its table cell.
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
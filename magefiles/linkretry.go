@@ -0,0 +1,171 @@
+//go:build mage
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// linkCheckConfig controls how hard checkLinksParallel tries before calling
+// an external link dead.
+type linkCheckConfig struct {
+	Concurrency int           // requests in flight across all hosts
+	PerHost     int           // requests in flight to any one host
+	Retries     int           // extra attempts after a network error, 429 or 5xx
+	Timeout     time.Duration // per attempt
+	Backoff     time.Duration // wait before the first retry; doubles after each
+	MaxWait     time.Duration // longest wait between attempts, including Retry-After
+}
+
+func linkCheckConfigFromEnv() (linkCheckConfig, error) {
+	cfg := linkCheckConfig{Concurrency: 5}
+	var err error
+	if cfg.PerHost, err = envInt("LINKS_PER_HOST", 2); err != nil {
+		return cfg, err
+	}
+	if cfg.Retries, err = envInt("LINKS_RETRIES", 2); err != nil {
+		return cfg, err
+	}
+	if cfg.Timeout, err = envDuration("LINKS_TIMEOUT", 10*time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.Backoff, err = envDuration("LINKS_BACKOFF", time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxWait, err = envDuration("LINKS_MAX_WAIT", 30*time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.PerHost < 1 {
+		return cfg, fmt.Errorf("LINKS_PER_HOST must be at least 1")
+	}
+	return cfg, nil
+}
+
+func envInt(name string, def int) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q; use a whole number", name, v)
+	}
+	return n, nil
+}
+
+// backoff is how long to wait before retry number attempt+1: Backoff doubled
+// per attempt, capped at MaxWait, with the upper half jittered so parallel
+// checks of one host don't retry in lockstep.
+func (cfg linkCheckConfig) backoff(attempt int) time.Duration {
+	d := cfg.Backoff << attempt
+	if d <= 0 || d > cfg.MaxWait {
+		d = cfg.MaxWait
+	}
+	if d < 2 {
+		return d
+	}
+	return d/2 + rand.N(d/2)
+}
+
+// hostLimiter caps the requests in flight to each host, and holds back every
+// request to a host that asked us to slow down with Retry-After.
+type hostLimiter struct {
+	perHost int
+	mu      sync.Mutex
+	hosts   map[string]*hostSlot
+}
+
+type hostSlot struct {
+	sem       chan struct{}
+	notBefore time.Time
+}
+
+func newHostLimiter(perHost int) *hostLimiter {
+	return &hostLimiter{perHost: perHost, hosts: make(map[string]*hostSlot)}
+}
+
+func (l *hostLimiter) slot(host string) *hostSlot {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := l.hosts[host]
+	if s == nil {
+		s = &hostSlot{sem: make(chan struct{}, l.perHost)}
+		l.hosts[host] = s
+	}
+	return s
+}
+
+// acquire waits for a free slot for host and for any Retry-After pause to
+// pass. The returned func releases the slot.
+func (l *hostLimiter) acquire(host string) func() {
+	s := l.slot(host)
+	s.sem <- struct{}{}
+	for {
+		l.mu.Lock()
+		wait := time.Until(s.notBefore)
+		l.mu.Unlock()
+		if wait <= 0 {
+			break
+		}
+		time.Sleep(wait)
+	}
+	return func() { <-s.sem }
+}
+
+// pause holds back requests to host for d.
+func (l *hostLimiter) pause(host string, d time.Duration) {
+	s := l.slot(host)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(s.notBefore) {
+		s.notBefore = until
+	}
+}
+
+// errTooManyRedirects is returned by the link checker's CheckRedirect.
+var errTooManyRedirects = errors.New("too many redirects")
+
+// retryableStatus reports whether an HTTP status may clear up on its own.
+func retryableStatus(code int) bool {
+	return code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500
+}
+
+// retryableError reports whether a request error may clear up on its own:
+// timeouts, reset or refused connections and responses cut short. Anything
+// else, like a certificate error, a domain that no longer exists or a
+// redirect loop, won't.
+func retryableError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// retryAfter parses a Retry-After header, given in seconds or as an HTTP
+// date. It returns 0 when there is none.
+func retryAfter(resp *http.Response) time.Duration {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
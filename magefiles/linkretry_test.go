@@ -0,0 +1,78 @@
+//go:build mage
+
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+// getError returns the error from fetching url with client.
+func getError(t *testing.T, client *http.Client, url string) error {
+	t.Helper()
+	resp, err := client.Get(url)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatalf("GET %s succeeded, want an error", url)
+	}
+	return err
+}
+
+func TestRetryableError(t *testing.T) {
+	urlErr := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://example.com/", Err: err}
+	}
+	opErr := func(op string, errno syscall.Errno) error {
+		return urlErr(&net.OpError{Op: op, Net: "tcp", Err: os.NewSyscallError(op, errno)})
+	}
+
+	tests := []struct {
+		name string
+		err  func(t *testing.T) error
+		want bool
+	}{
+		{"timeout", func(*testing.T) error { return urlErr(context.DeadlineExceeded) }, true},
+		{"connection reset", func(*testing.T) error { return opErr("read", syscall.ECONNRESET) }, true},
+		{"connection refused", func(*testing.T) error { return opErr("dial", syscall.ECONNREFUSED) }, true},
+		{"unexpected EOF", func(*testing.T) error { return urlErr(io.ErrUnexpectedEOF) }, true},
+		{"refused by a closed server", func(t *testing.T) error {
+			srv := httptest.NewServer(http.NotFoundHandler())
+			srv.Close()
+			return getError(t, http.DefaultClient, srv.URL)
+		}, true},
+
+		{"unknown certificate authority", func(*testing.T) error { return urlErr(x509.UnknownAuthorityError{}) }, false},
+		{"wrong host certificate", func(*testing.T) error { return urlErr(x509.HostnameError{Host: "example.com"}) }, false},
+		{"self-signed server", func(t *testing.T) error {
+			srv := httptest.NewUnstartedServer(http.NotFoundHandler())
+			srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+			srv.StartTLS()
+			defer srv.Close()
+			return getError(t, http.DefaultClient, srv.URL)
+		}, false},
+		{"domain not found", func(*testing.T) error {
+			return urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "gone.example", IsNotFound: true}})
+		}, false},
+		{"too many redirects", func(*testing.T) error { return urlErr(errTooManyRedirects) }, false},
+		{"malformed URL", func(t *testing.T) error { return getError(t, http.DefaultClient, "ftp://example.com/") }, false},
+		{"other error", func(*testing.T) error { return urlErr(errors.New("something else")) }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.err(t)
+			if got := retryableError(err); got != tt.want {
+				t.Errorf("retryableError(%v) = %v, want %v", err, got, tt.want)
+			}
+		})
+	}
+}
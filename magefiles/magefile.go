@@ -12,9 +12,9 @@ import (
 
 var Default = Build
 
-// Build builds TypeScript and Hugo site
+// Build validates events, builds TypeScript and Hugo site
 func Build() error {
-	mg.Deps(BuildTS)
+	mg.Deps(ValidateEvents, BuildTS)
 	fmt.Println("Building Hugo site...")
 	return sh.RunV("hugo", "--gc", "--minify")
 }
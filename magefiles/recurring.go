@@ -0,0 +1,649 @@
+//go:build mage
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const recurringFile = "data/recurring.yaml"
+
+// AddRecurringEvents generates the recurring event series defined in
+// data/recurring.yaml for a given year.
+// Idempotent: skips events whose title and date already exist in events.md.
+//
+// Environment variables:
+//
+//	DRY_RUN                1 to print the new entries and a diff instead of writing
+//	RECURRING_SUMMARY      path to write a JSON summary of added/skipped events
+//	RECURRING_SHIFT2BIKES  1 to create one Shift2Bikes event per series with
+//	                       shift2bikes fields and shift_mode create (or unset),
+//	                       covering all its new dates, and
+//	                       link each new entry to its date
+//
+// Usage: mage addRecurringEvents 2027
+func AddRecurringEvents(year int) error {
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.Local)
+	to := time.Date(year, time.December, 31, 0, 0, 0, 0, time.Local)
+	return runRecurring(from, to)
+}
+
+// AddRecurringRange is AddRecurringEvents for an inclusive date range, e.g. the
+// rest of a season. Accepts the same environment variables.
+//
+// Usage: mage addRecurringRange 2026-09-01 2027-03-31
+func AddRecurringRange(from, to string) error {
+	start, err := time.ParseInLocation("2006-01-02", from, time.Local)
+	if err != nil {
+		return fmt.Errorf("invalid start date %q; use YYYY-MM-DD", from)
+	}
+	end, err := time.ParseInLocation("2006-01-02", to, time.Local)
+	if err != nil {
+		return fmt.Errorf("invalid end date %q; use YYYY-MM-DD", to)
+	}
+	if end.Before(start) {
+		return fmt.Errorf("end date %s is before start date %s", to, from)
+	}
+	return runRecurring(start, end)
+}
+
+func runRecurring(from, to time.Time) error {
+	cfg, err := loadRecurringConfig(recurringFile)
+	if err != nil {
+		return err
+	}
+	doc, err := loadEventsDoc(eventsFile)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Generating recurring events from %s to %s...\n", from.Format("2006-01-02"), to.Format("2006-01-02"))
+
+	summary, added, err := generateRecurring(doc, cfg, from, to)
+	if err != nil {
+		return err
+	}
+	summary.DryRun = dryRun()
+
+	if summary.DryRun && len(added) > 0 {
+		fmt.Println("\nNew entries:")
+		for _, ev := range added {
+			fmt.Printf("\n%s\n", strings.Join(formatEventYAML(ev.eventEntry, doc.dashIndent, doc.fieldIndent), "\n"))
+		}
+	}
+	var shiftErr error
+	if envTrue("RECURRING_SHIFT2BIKES") && len(added) > 0 {
+		// Keep whatever was created even if a later series fails
+		shiftErr = createRecurringShift2Bikes(cfg, summary, added)
+	}
+	if len(added) > 0 {
+		if err := doc.Commit(); err != nil {
+			return err
+		}
+	}
+	if shiftErr != nil {
+		return shiftErr
+	}
+
+	if path := os.Getenv("RECURRING_SUMMARY"); path != "" {
+		data, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to write summary: %w", err)
+		}
+	}
+
+	moved := 0
+	for _, item := range summary.Added {
+		if item.MovedFrom != "" {
+			moved++
+		}
+	}
+	fmt.Printf("\nAdded %d events, skipped %d existing, left out %d and moved %d for holidays/exceptions.\n", len(summary.Added), len(summary.Existing), len(summary.Excluded), moved)
+	return nil
+}
+
+// recurringSummary is the JSON written to RECURRING_SUMMARY.
+type recurringSummary struct {
+	From     string          `json:"from"`
+	To       string          `json:"to"`
+	DryRun   bool            `json:"dry_run"`
+	Added    []recurringItem `json:"added"`
+	Existing []recurringItem `json:"existing"`
+	Excluded []recurringItem `json:"excluded"`
+}
+
+type recurringItem struct {
+	Series    string `json:"series"`
+	Title     string `json:"title"`
+	Date      string `json:"date"`
+	MovedFrom string `json:"moved_from,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+type recurringConfig struct {
+	Exceptions []recurringException `yaml:"exceptions"`
+	Series     []*recurringSeries   `yaml:"series"`
+
+	exceptions map[string]recurringException
+}
+
+// recurringException changes a single date: "skip" leaves it out and "move"
+// shifts it to "to", or to the next weekday when "to" is empty.
+type recurringException struct {
+	Date   string `yaml:"date"`
+	Action string `yaml:"action"`
+	To     string `yaml:"to"`
+	Reason string `yaml:"reason"`
+}
+
+// holidayRule skips or moves dates that fall on a US federal holiday.
+type holidayRule struct {
+	Action string   `yaml:"action"`
+	Names  []string `yaml:"names"` // holiday ids; empty means all of them
+}
+
+// recurringSeries is one entry of data/recurring.yaml.
+type recurringSeries struct {
+	Name         string               `yaml:"name"`
+	Type         string               `yaml:"type"`
+	Weekday      string               `yaml:"weekday"`
+	Ordinals     []string             `yaml:"ordinals"`
+	Follows      string               `yaml:"follows"`
+	Title        string               `yaml:"title"`
+	Section      string               `yaml:"section"`
+	Start        string               `yaml:"start"`
+	End          string               `yaml:"end"`
+	StartAddress string               `yaml:"start_address"`
+	Tags         []string             `yaml:"tags"`
+	Shift2Bikes  map[string]any       `yaml:"shift2bikes"`
+	ShiftMode    string               `yaml:"shift_mode"`
+	Skip         []string             `yaml:"skip"`
+	Exceptions   []recurringException `yaml:"exceptions"`
+	Holidays     *holidayRule         `yaml:"holidays"`
+
+	weekday    time.Weekday
+	ordinals   []int // 1-5, or -1 for the last occurrence
+	skip       map[string]bool
+	exceptions map[string]recurringException
+	holidays   map[string]bool
+	title      *template.Template
+	follows    *recurringSeries
+}
+
+// occurrence is one scheduled date of a series after exceptions are applied.
+type occurrence struct {
+	date     time.Time // date the event happens; zero when left out
+	original time.Time // date the recurrence rule produced
+	note     string    // why the date was left out or moved
+}
+
+func loadRecurringConfig(path string) (*recurringConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg recurringConfig
+	if err := yaml.Unmarshal(content, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if cfg.exceptions, err = compileExceptions(cfg.Exceptions); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	tmpl, err := loadEventTemplates(eventTemplatesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*recurringSeries)
+	for _, s := range cfg.Series {
+		if s.Type != "" {
+			t := tmpl.Type(s.Type)
+			if t == nil {
+				return nil, fmt.Errorf("%s: series %q: unknown type %q (see %s)", path, s.Name, s.Type, eventTemplatesFile)
+			}
+			s.applyType(t)
+		}
+		if err := s.compile(byName); err != nil {
+			return nil, fmt.Errorf("%s: series %q: %w", path, s.Name, err)
+		}
+		byName[s.Name] = s
+	}
+	return &cfg, nil
+}
+
+// applyType fills in every field the series leaves empty from an event type.
+func (s *recurringSeries) applyType(t *eventType) {
+	fill := func(field *string, v string) {
+		if *field == "" {
+			*field = v
+		}
+	}
+	fill(&s.Title, t.Title)
+	fill(&s.Section, t.Section)
+	if s.Follows == "" {
+		fill(&s.Weekday, t.Weekday)
+	}
+	fill(&s.Start, t.Start)
+	fill(&s.End, t.End)
+	fill(&s.StartAddress, t.venue.mapAddress())
+	if s.Tags == nil {
+		s.Tags = t.Tags
+	}
+	if s.Shift2Bikes == nil {
+		s.Shift2Bikes = t.shift2bikesFields()
+	}
+	fill(&s.ShiftMode, t.ShiftMode)
+}
+
+func (s *recurringSeries) compile(earlier map[string]*recurringSeries) error {
+	if s.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+	if _, dup := earlier[s.Name]; dup {
+		return fmt.Errorf("duplicate name")
+	}
+
+	if s.Follows != "" {
+		s.follows = earlier[s.Follows]
+		if s.follows == nil {
+			return fmt.Errorf("follows unknown series %q (it must be listed earlier)", s.Follows)
+		}
+	} else {
+		wd, err := parseWeekday(s.Weekday)
+		if err != nil {
+			return err
+		}
+		s.weekday = wd
+		if len(s.Ordinals) == 0 {
+			return fmt.Errorf("missing ordinals")
+		}
+		for _, o := range s.Ordinals {
+			if strings.EqualFold(o, "last") {
+				s.ordinals = append(s.ordinals, -1)
+				continue
+			}
+			n, err := strconv.Atoi(o)
+			if err != nil || n < 1 || n > 5 {
+				return fmt.Errorf("invalid ordinal %q; use 1-5 or last", o)
+			}
+			s.ordinals = append(s.ordinals, n)
+		}
+	}
+
+	s.skip = make(map[string]bool)
+	for _, d := range s.Skip {
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			return fmt.Errorf("invalid skip date %q; use YYYY-MM-DD", d)
+		}
+		s.skip[d] = true
+	}
+	exceptions, err := compileExceptions(s.Exceptions)
+	if err != nil {
+		return err
+	}
+	s.exceptions = exceptions
+
+	switch s.ShiftMode {
+	case "", "create", "existing", "skip":
+	default:
+		return fmt.Errorf("invalid shift_mode %q; use create, existing, or skip", s.ShiftMode)
+	}
+
+	if s.Holidays != nil {
+		if s.Holidays.Action != "skip" && s.Holidays.Action != "move" {
+			return fmt.Errorf("holidays.action must be skip or move, not %q", s.Holidays.Action)
+		}
+		s.holidays = make(map[string]bool)
+		for _, name := range s.Holidays.Names {
+			if _, ok := federalHolidayNames[name]; !ok {
+				return fmt.Errorf("unknown holiday %q", name)
+			}
+			s.holidays[name] = true
+		}
+		if len(s.holidays) == 0 {
+			for name := range federalHolidayNames {
+				s.holidays[name] = true
+			}
+		}
+	}
+
+	if s.Title == "" {
+		return fmt.Errorf("missing title")
+	}
+	if s.Section == "" {
+		return fmt.Errorf("missing section")
+	}
+	t, err := template.New(s.Name).Option("missingkey=error").Parse(s.Title)
+	if err != nil {
+		return fmt.Errorf("invalid title template: %w", err)
+	}
+	s.title = t
+	return nil
+}
+
+func compileExceptions(list []recurringException) (map[string]recurringException, error) {
+	exceptions := make(map[string]recurringException)
+	for _, e := range list {
+		if _, err := time.Parse("2006-01-02", e.Date); err != nil {
+			return nil, fmt.Errorf("exception: invalid date %q; use YYYY-MM-DD", e.Date)
+		}
+		switch e.Action {
+		case "skip":
+		case "move":
+			if e.To != "" {
+				if _, err := time.Parse("2006-01-02", e.To); err != nil {
+					return nil, fmt.Errorf("exception %s: invalid move target %q; use YYYY-MM-DD", e.Date, e.To)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("exception %s: action must be skip or move, not %q", e.Date, e.Action)
+		}
+		exceptions[e.Date] = e
+	}
+	return exceptions, nil
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		if strings.EqualFold(name, wd.String()) || strings.EqualFold(name, wd.String()[:3]) {
+			return wd, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", name)
+}
+
+// occurrences returns the series' dates between from and to inclusive, in
+// order, with skips, exceptions and holidays applied. Series exceptions take
+// precedence over config-wide ones, and both over holiday rules.
+func (s *recurringSeries) occurrences(cfg *recurringConfig, from, to time.Time) []occurrence {
+	if s.follows != nil {
+		var occs []occurrence
+		for _, o := range s.follows.occurrences(cfg, from, to) {
+			if !o.date.IsZero() && s.skip[o.date.Format("2006-01-02")] {
+				o.date, o.note = time.Time{}, "in skip list"
+			}
+			occs = append(occs, o)
+		}
+		return occs
+	}
+
+	var dates []time.Time
+	for month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.Local); !month.After(to); month = month.AddDate(0, 1, 0) {
+		var inMonth []time.Time
+		for _, n := range s.ordinals {
+			var d time.Time
+			if n == -1 {
+				d = lastWeekday(month.Year(), month.Month(), s.weekday)
+			} else {
+				d = nthWeekday(month.Year(), month.Month(), s.weekday, n)
+			}
+			if d.IsZero() || d.Before(from) || d.After(to) {
+				continue
+			}
+			inMonth = insertDate(inMonth, d)
+		}
+		dates = append(dates, inMonth...)
+	}
+
+	holidays := make(map[int]map[string]string)
+	var occs []occurrence
+	for _, d := range dates {
+		key := d.Format("2006-01-02")
+		o := occurrence{date: d, original: d}
+
+		e, ok := s.exceptions[key]
+		if !ok {
+			e, ok = cfg.exceptions[key]
+		}
+		if !ok && s.holidays != nil {
+			if holidays[d.Year()] == nil {
+				holidays[d.Year()] = usFederalHolidays(d.Year())
+			}
+			if id := holidays[d.Year()][key]; s.holidays[id] {
+				e, ok = recurringException{Date: key, Action: s.Holidays.Action, Reason: federalHolidayNames[id]}, true
+			}
+		}
+
+		switch {
+		case s.skip[key]:
+			o.date, o.note = time.Time{}, "in skip list"
+		case ok && e.Action == "skip":
+			o.date, o.note = time.Time{}, e.Reason
+		case ok && e.Action == "move":
+			o.date, o.note = nextWeekday(d), e.Reason
+			if e.To != "" {
+				o.date, _ = time.ParseInLocation("2006-01-02", e.To, time.Local)
+			}
+		}
+		occs = append(occs, o)
+	}
+	return occs
+}
+
+// insertDate adds d to a sorted slice unless it is already present, which
+// happens when e.g. both 4 and "last" pick the same day.
+func insertDate(dates []time.Time, d time.Time) []time.Time {
+	for i, existing := range dates {
+		if existing.Equal(d) {
+			return dates
+		}
+		if d.Before(existing) {
+			return append(dates[:i], append([]time.Time{d}, dates[i:]...)...)
+		}
+	}
+	return append(dates, d)
+}
+
+// entry renders the events.md entry for one date of the series.
+func (s *recurringSeries) entry(d time.Time) (eventEntry, error) {
+	var title bytes.Buffer
+	if err := s.title.Execute(&title, newEventTemplateData(d)); err != nil {
+		return eventEntry{}, fmt.Errorf("series %q: %w", s.Name, err)
+	}
+	return eventEntry{
+		Title:        title.String(),
+		Date:         d.Format(eventDateLayout),
+		Start:        s.Start,
+		End:          s.End,
+		StartAddress: s.StartAddress,
+		Tags:         append([]string(nil), s.Tags...),
+	}, nil
+}
+
+// payload renders the series' Shift2Bikes template for one or more dates, or
+// returns nil when the series has none.
+func (s *recurringSeries) payload(dates ...time.Time) (*shift2bikesPayload, error) {
+	if s.Shift2Bikes == nil {
+		return nil, nil
+	}
+	p, err := renderShift2BikesPayload(s.Shift2Bikes, dates)
+	if err != nil {
+		return nil, fmt.Errorf("series %q: %w", s.Name, err)
+	}
+	return p, nil
+}
+
+// generateRecurring inserts every series' events between from and to that
+// are not already in doc, reporting dates left out or moved by exceptions.
+// It returns the summary and the inserted events.
+func generateRecurring(doc *eventsDoc, cfg *recurringConfig, from, to time.Time) (recurringSummary, []*docEvent, error) {
+	summary := recurringSummary{
+		From:     from.Format("2006-01-02"),
+		To:       to.Format("2006-01-02"),
+		Added:    []recurringItem{},
+		Existing: []recurringItem{},
+		Excluded: []recurringItem{},
+	}
+	var added []*docEvent
+
+	for _, s := range cfg.Series {
+		fmt.Printf("\n%s:\n", s.Name)
+		for _, o := range s.occurrences(cfg, from, to) {
+			planned, err := s.entry(o.original)
+			if err != nil {
+				return summary, nil, err
+			}
+			if o.date.IsZero() {
+				fmt.Printf("  - %s (%s) left out: %s\n", planned.Title, planned.Date, o.note)
+				summary.Excluded = append(summary.Excluded, recurringItem{Series: s.Name, Title: planned.Title, Date: planned.Date, Reason: o.note})
+				continue
+			}
+
+			entry, err := s.entry(o.date)
+			if err != nil {
+				return summary, nil, err
+			}
+			// A moved date counts as existing if the event is still on the original date
+			existing := entry
+			if doc.FindEvent(entry.Title, entry.Date) == nil {
+				existing = planned
+			}
+			if doc.FindEvent(existing.Title, existing.Date) != nil {
+				fmt.Printf("  = %s (%s) already exists, skipped\n", existing.Title, existing.Date)
+				summary.Existing = append(summary.Existing, recurringItem{Series: s.Name, Title: existing.Title, Date: existing.Date})
+				continue
+			}
+
+			var ev *docEvent
+			if s.follows != nil {
+				prev, err := s.follows.entry(o.date)
+				if err != nil {
+					return summary, nil, err
+				}
+				if p := doc.FindEvent(prev.Title, prev.Date); p != nil {
+					ev = doc.InsertAfter(p, entry)
+				}
+			}
+			if ev == nil {
+				if ev, err = doc.Insert(entry, s.Section); err != nil {
+					return summary, nil, fmt.Errorf("failed to add %s: %w", entry.Title, err)
+				}
+			}
+			added = append(added, ev)
+
+			item := recurringItem{Series: s.Name, Title: entry.Title, Date: entry.Date}
+			if !o.date.Equal(o.original) {
+				item.MovedFrom, item.Reason = planned.Date, o.note
+				fmt.Printf("  > %s (%s) moved from %s: %s\n", entry.Title, entry.Date, planned.Date, o.note)
+			} else {
+				fmt.Printf("  + %s (%s)\n", entry.Title, entry.Date)
+			}
+			summary.Added = append(summary.Added, item)
+		}
+	}
+	return summary, added, nil
+}
+
+// createRecurringShift2Bikes creates a Shift2Bikes event for each series'
+// newly added dates and sets each added entry's URL to its own date. Series
+// whose shift_mode is existing or skip are left alone. added and
+// summary.Added are parallel. With DRY_RUN it only reports what it would
+// create.
+func createRecurringShift2Bikes(cfg *recurringConfig, summary recurringSummary, added []*docEvent) error {
+	created := false
+	for _, s := range cfg.Series {
+		if s.Shift2Bikes == nil {
+			continue
+		}
+		// Like addEvent, only series whose shift_mode is create (or unset)
+		// get a new Shift2Bikes event
+		if s.ShiftMode != "" && s.ShiftMode != "create" {
+			fmt.Printf("\nNot creating a Shift2Bikes event for %s (shift_mode: %s)\n", s.Name, s.ShiftMode)
+			continue
+		}
+		var events []*docEvent
+		var dates []time.Time
+		for i, item := range summary.Added {
+			if item.Series != s.Name {
+				continue
+			}
+			d, err := time.ParseInLocation(eventDateLayout, added[i].Date, time.Local)
+			if err != nil {
+				return err
+			}
+			events = append(events, added[i])
+			dates = append(dates, d)
+		}
+		if len(dates) == 0 {
+			continue
+		}
+
+		payload, err := s.payload(dates...)
+		if err != nil {
+			return err
+		}
+		if payload.Address == "" {
+			payload.Address = s.StartAddress
+		}
+		if payload.Address == "" {
+			return fmt.Errorf("series %q: no address for Shift2Bikes; set start_address or the venue's address", s.Name)
+		}
+		if dryRun() {
+			fmt.Printf("\nDRY_RUN: would create Shift2Bikes event %q with %d dates for %s\n", payload.Title, len(dates), s.Name)
+			continue
+		}
+		fmt.Printf("\nCreating Shift2Bikes event %q with %d dates for %s...\n", payload.Title, len(dates), s.Name)
+		urls, err := submitToShift2Bikes(payload)
+		if err != nil {
+			return fmt.Errorf("series %q: Shift2Bikes API error: %w", s.Name, err)
+		}
+		created = true
+		for _, ev := range events {
+			if ev.URL = urls[entryAPIDate(ev.eventEntry)]; ev.URL == "" {
+				fmt.Printf("WARNING: no Shift2Bikes date ID returned for %s (%s)\n", ev.Title, ev.Date)
+			}
+		}
+	}
+	if created {
+		fmt.Println("\nREMINDER: Check the Ride Westside Gmail for the confirmation emails")
+		fmt.Println("and click the links to publish the events on the Shift2Bikes calendar!")
+	}
+	return nil
+}
+
+// nthWeekday returns the nth occurrence of a weekday in the given month/year.
+// Returns zero time if the nth occurrence doesn't exist in that month.
+func nthWeekday(year int, month time.Month, weekday time.Weekday, n int) time.Time {
+	// Start at the 1st of the month
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.Local)
+
+	// Find the first occurrence of the target weekday
+	offset := int(weekday) - int(first.Weekday())
+	if offset < 0 {
+		offset += 7
+	}
+	firstOccurrence := first.AddDate(0, 0, offset)
+
+	// Jump to the nth occurrence
+	target := firstOccurrence.AddDate(0, 0, (n-1)*7)
+
+	// Verify it's still in the same month
+	if target.Month() != month {
+		return time.Time{}
+	}
+	return target
+}
+
+// lastWeekday returns the last occurrence of a weekday in the given month/year.
+func lastWeekday(year int, month time.Month, weekday time.Weekday) time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.Local)
+	offset := int(last.Weekday()) - int(weekday)
+	if offset < 0 {
+		offset += 7
+	}
+	return last.AddDate(0, 0, -offset)
+}
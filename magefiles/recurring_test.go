@@ -0,0 +1,246 @@
+//go:build mage
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUSFederalHolidays(t *testing.T) {
+	tests := []struct {
+		year int
+		want map[string]string
+	}{
+		{2026, map[string]string{
+			"2026-01-01": "new-years-day",
+			"2026-01-19": "mlk-day",
+			"2026-02-16": "presidents-day",
+			"2026-05-25": "memorial-day",
+			"2026-06-19": "juneteenth",
+			"2026-07-03": "independence-day", // Saturday, observed Friday
+			"2026-09-07": "labor-day",
+			"2026-10-12": "columbus-day",
+			"2026-11-11": "veterans-day",
+			"2026-11-26": "thanksgiving",
+			"2026-12-25": "christmas-day",
+		}},
+		{2027, map[string]string{
+			"2027-01-01": "new-years-day",
+			"2027-01-18": "mlk-day",
+			"2027-02-15": "presidents-day",
+			"2027-05-31": "memorial-day",
+			"2027-06-18": "juneteenth",       // Saturday, observed Friday
+			"2027-07-05": "independence-day", // Sunday, observed Monday
+			"2027-09-06": "labor-day",
+			"2027-10-11": "columbus-day",
+			"2027-11-11": "veterans-day",
+			"2027-11-25": "thanksgiving",
+			"2027-12-24": "christmas-day", // Saturday, observed Friday
+			"2027-12-31": "new-years-day", // January 1, 2028 is a Saturday
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.year), func(t *testing.T) {
+			got := usFederalHolidays(tt.year)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("usFederalHolidays(%d) =\n%v\nwant\n%v", tt.year, got, tt.want)
+			}
+			for _, id := range got {
+				if federalHolidayNames[id] == "" {
+					t.Errorf("holiday %q has no name", id)
+				}
+			}
+		})
+	}
+}
+
+func TestNthWeekday(t *testing.T) {
+	tests := []struct {
+		month time.Month
+		wd    time.Weekday
+		n     int // -1 for lastWeekday
+		want  string
+	}{
+		{time.May, time.Monday, 2, "2026-05-11"},
+		{time.May, time.Monday, 4, "2026-05-25"},
+		{time.May, time.Monday, -1, "2026-05-25"},
+		{time.August, time.Monday, 5, "2026-08-31"},
+		{time.September, time.Tuesday, 1, "2026-09-01"}, // the 1st is the weekday itself
+		{time.February, time.Monday, 5, ""},
+		{time.February, time.Monday, -1, "2026-02-23"},
+		{time.December, time.Thursday, -1, "2026-12-31"},
+	}
+	for _, tt := range tests {
+		var d time.Time
+		if tt.n == -1 {
+			d = lastWeekday(2026, tt.month, tt.wd)
+		} else {
+			d = nthWeekday(2026, tt.month, tt.wd, tt.n)
+		}
+		got := ""
+		if !d.IsZero() {
+			got = d.Format("2006-01-02")
+		}
+		if got != tt.want {
+			t.Errorf("%s %d of %s 2026 = %q, want %q", tt.wd, tt.n, tt.month, got, tt.want)
+		}
+	}
+}
+
+// testSeries compiles a series the way loadRecurringConfig does.
+func testSeries(t *testing.T, s *recurringSeries, earlier ...*recurringSeries) *recurringSeries {
+	t.Helper()
+	byName := make(map[string]*recurringSeries)
+	for _, e := range earlier {
+		byName[e.Name] = e
+	}
+	if s.Title == "" {
+		s.Title = "{{.Short}} Test"
+	}
+	s.Section = "# Test"
+	if err := s.compile(byName); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// formatOccurrences renders occurrences as "date", "original>date (note)"
+// for moves and "-original (note)" for dates left out.
+func formatOccurrences(occs []occurrence) string {
+	var out []string
+	for _, o := range occs {
+		orig := o.original.Format("2006-01-02")
+		switch {
+		case o.date.IsZero():
+			out = append(out, fmt.Sprintf("-%s (%s)", orig, o.note))
+		case !o.date.Equal(o.original):
+			out = append(out, fmt.Sprintf("%s>%s (%s)", orig, o.date.Format("2006-01-02"), o.note))
+		default:
+			out = append(out, orig)
+		}
+	}
+	return strings.Join(out, ", ")
+}
+
+func TestOccurrences(t *testing.T) {
+	day := func(s string) time.Time {
+		d, err := time.ParseInLocation("2006-01-02", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	cfg := func(exceptions ...recurringException) *recurringConfig {
+		c := &recurringConfig{}
+		var err error
+		if c.exceptions, err = compileExceptions(exceptions); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		series   *recurringSeries
+		cfg      *recurringConfig
+		from, to string
+		want     string
+	}{
+		{
+			name:   "2nd and 4th mondays",
+			series: &recurringSeries{Name: "s", Weekday: "monday", Ordinals: []string{"2", "4"}},
+			cfg:    cfg(), from: "2026-05-01", to: "2026-06-30",
+			want: "2026-05-11, 2026-05-25, 2026-06-08, 2026-06-22",
+		},
+		{
+			name:   "range cuts the month",
+			series: &recurringSeries{Name: "s", Weekday: "monday", Ordinals: []string{"2", "4"}},
+			cfg:    cfg(), from: "2026-05-12", to: "2026-06-08",
+			want: "2026-05-25, 2026-06-08",
+		},
+		{
+			name:   "4th and last are the same day in a four-monday month",
+			series: &recurringSeries{Name: "s", Weekday: "monday", Ordinals: []string{"last", "4"}},
+			cfg:    cfg(), from: "2026-02-01", to: "2026-03-31",
+			want: "2026-02-23, 2026-03-23, 2026-03-30",
+		},
+		{
+			name:   "5th only in months that have one",
+			series: &recurringSeries{Name: "s", Weekday: "monday", Ordinals: []string{"5"}},
+			cfg:    cfg(), from: "2026-06-01", to: "2026-09-30",
+			want: "2026-06-29, 2026-08-31",
+		},
+		{
+			name: "holiday move to the next weekday",
+			series: &recurringSeries{Name: "s", Weekday: "monday", Ordinals: []string{"2", "4"},
+				Holidays: &holidayRule{Action: "move", Names: []string{"memorial-day"}}},
+			cfg: cfg(), from: "2026-05-01", to: "2026-05-31",
+			want: "2026-05-11, 2026-05-25>2026-05-26 (Memorial Day)",
+		},
+		{
+			name: "holiday move only for the named holidays",
+			series: &recurringSeries{Name: "s", Weekday: "monday", Ordinals: []string{"1"},
+				Holidays: &holidayRule{Action: "move", Names: []string{"memorial-day"}}},
+			cfg: cfg(), from: "2026-09-01", to: "2026-09-30",
+			want: "2026-09-07",
+		},
+		{
+			name: "holiday skip on an observed date",
+			series: &recurringSeries{Name: "s", Weekday: "friday", Ordinals: []string{"1"},
+				Holidays: &holidayRule{Action: "skip"}},
+			cfg: cfg(), from: "2026-07-01", to: "2026-08-31",
+			want: "-2026-07-03 (Independence Day), 2026-08-07",
+		},
+		{
+			name: "next year's New Year's Day observed on December 31",
+			series: &recurringSeries{Name: "s", Weekday: "friday", Ordinals: []string{"4", "last"},
+				Holidays: &holidayRule{Action: "move"}},
+			cfg: cfg(), from: "2027-12-01", to: "2027-12-31",
+			want: "2027-12-24>2027-12-27 (Christmas Day), 2027-12-31>2028-01-03 (New Year's Day)",
+		},
+		{
+			name: "series exception beats config exception and holiday",
+			series: &recurringSeries{Name: "s", Weekday: "monday", Ordinals: []string{"4"},
+				Holidays:   &holidayRule{Action: "skip"},
+				Exceptions: []recurringException{{Date: "2026-05-25", Action: "move", To: "2026-05-27", Reason: "Park event"}}},
+			cfg: cfg(
+				recurringException{Date: "2026-05-25", Action: "skip", Reason: "Everyone's away"},
+				recurringException{Date: "2026-06-22", Action: "skip", Reason: "Everyone's away"},
+			),
+			from: "2026-05-01", to: "2026-06-30",
+			want: "2026-05-25>2026-05-27 (Park event), -2026-06-22 (Everyone's away)",
+		},
+		{
+			name: "skip list beats exceptions",
+			series: &recurringSeries{Name: "s", Weekday: "monday", Ordinals: []string{"2"},
+				Skip:       []string{"2026-06-08"},
+				Exceptions: []recurringException{{Date: "2026-06-08", Action: "move"}}},
+			cfg: cfg(), from: "2026-06-01", to: "2026-06-30",
+			want: "-2026-06-08 (in skip list)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testSeries(t, tt.series)
+			got := formatOccurrences(s.occurrences(tt.cfg, day(tt.from), day(tt.to)))
+			if got != tt.want {
+				t.Errorf("occurrences =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("follows", func(t *testing.T) {
+		lead := testSeries(t, &recurringSeries{Name: "lead", Weekday: "monday", Ordinals: []string{"2", "4"},
+			Holidays: &holidayRule{Action: "move", Names: []string{"memorial-day"}}})
+		ride := testSeries(t, &recurringSeries{Name: "ride", Follows: "lead", Skip: []string{"2026-05-11"}}, lead)
+
+		got := formatOccurrences(ride.occurrences(cfg(), day("2026-05-01"), day("2026-05-31")))
+		want := "-2026-05-11 (in skip list), 2026-05-25>2026-05-26 (Memorial Day)"
+		if got != want {
+			t.Errorf("occurrences =\n%s\nwant\n%s", got, want)
+		}
+	})
+}
@@ -0,0 +1,219 @@
+//go:build mage
+
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// scheduleKind is an event series that can appear as a URL column in a
+// schedule TSV.
+type scheduleKind struct {
+	name    string
+	headers []string // lower-case column headers that map to this kind
+	matches func(title string) bool
+	typ     string // event type in data/event-templates.yaml
+	follows string // name of the kind new entries are placed after, if present
+}
+
+var scheduleKinds = []scheduleKind{
+	{
+		name:    "Beaverton happy hour",
+		headers: []string{"bhh", "beaverton", "bike happy hour"},
+		matches: func(t string) bool { return strings.HasSuffix(t, " Bike Happy Hour") },
+		typ:     "beaverton",
+	},
+	{
+		name:    "post-happy hour ride",
+		headers: []string{"post hh ride", "post ride", "post-bike happy hour ride"},
+		matches: func(t string) bool { return strings.HasSuffix(t, " Post-Bike Happy Hour Ride") },
+		typ:     "post-ride",
+		follows: "Beaverton happy hour",
+	},
+	{
+		name:    "Tigard happy hour",
+		headers: []string{"thh", "tigard", "tigard happy hour"},
+		matches: func(t string) bool { return strings.HasSuffix(t, " Tigard Happy Hour") },
+		typ:     "tigard",
+	},
+}
+
+// ImportSchedule reconciles a tab-separated schedule (such as upcoming.tsv)
+// with events.md: missing URLs are filled in, missing events are created and
+// URLs that disagree with events.md are reported as conflicts.
+//
+// The first row is a header. A "Date" column holds M/D/YYYY dates and each
+// other recognized column (BHH, Post HH Ride, Tigard) holds event URLs.
+// Set DRY_RUN=1 to print the resulting diff without writing.
+//
+// Usage: mage importSchedule upcoming.tsv
+func ImportSchedule(path string) error {
+	rows, err := readScheduleTSV(path)
+	if err != nil {
+		return err
+	}
+
+	tmpl, err := loadEventTemplates(eventTemplatesFile)
+	if err != nil {
+		return err
+	}
+	doc, err := loadEventsDoc(eventsFile)
+	if err != nil {
+		return err
+	}
+
+	created, filled, unchanged, conflicts := 0, 0, 0, 0
+	for _, row := range rows {
+		d := newParsedDate(row.date)
+		// Process happy hours before their post rides so rides land next to them
+		for _, kind := range scheduleKinds {
+			url := row.urls[kind.name]
+			if url == "" {
+				continue
+			}
+			ev := findScheduledEvent(doc, kind, d.display)
+			switch {
+			case ev == nil:
+				typ := tmpl.Type(kind.typ)
+				if typ == nil {
+					return fmt.Errorf("%s: unknown type %q", eventTemplatesFile, kind.typ)
+				}
+				entry, err := typ.entry(row.date)
+				if err != nil {
+					return err
+				}
+				entry.URL = url
+				if err := insertScheduled(doc, kind, entry, d, typ.Section); err != nil {
+					return err
+				}
+				fmt.Printf("  + %s (%s)\n", entry.Title, entry.Date)
+				created++
+			case ev.URL == "":
+				ev.URL = url
+				fmt.Printf("  ~ %s: url set to %s\n", ev.Title, url)
+				filled++
+			case sameEventURL(ev.URL, url):
+				unchanged++
+			default:
+				fmt.Printf("  ! %s (%s:%d): events.md has %s, schedule has %s\n", ev.Title, eventsFile, ev.lineOf("url"), ev.URL, url)
+				conflicts++
+			}
+		}
+	}
+
+	if err := doc.Commit(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nCreated %d events, filled %d URLs, %d unchanged, %d conflicts.\n", created, filled, unchanged, conflicts)
+	return nil
+}
+
+type scheduleRow struct {
+	date time.Time
+	urls map[string]string // scheduleKind.name -> URL
+}
+
+func readScheduleTSV(path string) ([]scheduleRow, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comma = '\t'
+	r.LazyQuotes = true
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%s is empty", path)
+	}
+
+	dateCol := -1
+	kindCols := make(map[int]string)
+	for i, h := range records[0] {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h == "date" {
+			dateCol = i
+			continue
+		}
+		found := false
+		for _, kind := range scheduleKinds {
+			for _, kh := range kind.headers {
+				if h == kh {
+					kindCols[i] = kind.name
+					found = true
+				}
+			}
+		}
+		if !found && h != "" {
+			fmt.Printf("  ? %s: ignoring unknown column %q\n", path, records[0][i])
+		}
+	}
+	if dateCol == -1 {
+		return nil, fmt.Errorf("%s: no Date column in header", path)
+	}
+
+	var rows []scheduleRow
+	for n, rec := range records[1:] {
+		line := n + 2
+		if dateCol >= len(rec) || strings.TrimSpace(rec[dateCol]) == "" {
+			continue
+		}
+		t, err := time.ParseInLocation("1/2/2006", strings.TrimSpace(rec[dateCol]), time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid date %q; use M/D/YYYY", path, line, rec[dateCol])
+		}
+		row := scheduleRow{date: t, urls: make(map[string]string)}
+		for col, name := range kindCols {
+			if col < len(rec) {
+				row.urls[name] = strings.TrimSpace(rec[col])
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func findScheduledEvent(doc *eventsDoc, kind scheduleKind, date string) *docEvent {
+	for _, ev := range doc.Events() {
+		if ev.Date == date && kind.matches(ev.Title) {
+			return ev
+		}
+	}
+	return nil
+}
+
+// insertScheduled adds a new event, placing it directly after the event it
+// follows (a post ride after its happy hour) when there is one.
+func insertScheduled(doc *eventsDoc, kind scheduleKind, entry eventEntry, d parsedDate, section string) error {
+	for _, k := range scheduleKinds {
+		if k.name != kind.follows {
+			continue
+		}
+		if prev := findScheduledEvent(doc, k, d.display); prev != nil {
+			doc.InsertAfter(prev, entry)
+			return nil
+		}
+	}
+	_, err := doc.Insert(entry, section)
+	return err
+}
+
+// sameEventURL compares URLs, treating www/non-www Shift2Bikes links to the
+// same event as equal.
+func sameEventURL(a, b string) bool {
+	if a == b {
+		return true
+	}
+	ma, mb := shift2bikesEventRegex.FindStringSubmatch(a), shift2bikesEventRegex.FindStringSubmatch(b)
+	return ma != nil && mb != nil && ma[1] == mb[1]
+}
@@ -0,0 +1,329 @@
+//go:build mage
+
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/magefile/mage/mg"
+)
+
+const (
+	// plainSecretsFile is git-ignored and used for local runs.
+	plainSecretsFile = ".shift2bikes-secrets.json"
+	// encryptedSecretsDir is committed and used when a passphrase is set, so
+	// CI runs can share secrets through pull requests. Each event has its own
+	// <event id>.enc file, so pull requests adding different events never
+	// touch the same file.
+	encryptedSecretsDir = "shift2bikes-secrets"
+
+	secretsPassphraseEnv = "SHIFT2BIKES_SECRETS_PASSPHRASE"
+	secretsKDFIterations = 600000
+)
+
+// shiftSecret is what is needed to edit a Shift2Bikes event later.
+type shiftSecret struct {
+	EventID string            `json:"event_id"`
+	Secret  string            `json:"secret"`
+	Title   string            `json:"title"`
+	Dates   map[string]string `json:"dates"` // YYYY-MM-DD -> per-date id
+	Created string            `json:"created"`
+}
+
+// URLs returns the calendar URLs of every date of the event, in date order.
+func (s shiftSecret) URLs() []string {
+	dates := make([]string, 0, len(s.Dates))
+	for d := range s.Dates {
+		dates = append(dates, d)
+	}
+	sort.Strings(dates)
+	urls := make([]string, len(dates))
+	for i, d := range dates {
+		urls[i] = shift2bikesEventURL(s.Dates[d])
+	}
+	return urls
+}
+
+// shiftSecretStore is the set of known event secrets, keyed by event id.
+// It is stored in plainSecretsFile, or in encryptedSecretsDir when
+// SHIFT2BIKES_SECRETS_PASSPHRASE is set.
+type shiftSecretStore struct {
+	path       string
+	passphrase string
+	changed    map[string]bool        // event ids to write on Save
+	Events     map[string]shiftSecret `json:"events"`
+}
+
+func loadShiftSecrets() (*shiftSecretStore, error) {
+	store := &shiftSecretStore{path: plainSecretsFile, changed: make(map[string]bool), Events: make(map[string]shiftSecret)}
+	if p := os.Getenv(secretsPassphraseEnv); p != "" {
+		store.path, store.passphrase = encryptedSecretsDir, p
+		return store, store.loadEncrypted()
+	}
+
+	data, err := os.ReadFile(store.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", store.path, err)
+	}
+	if store.Events == nil {
+		store.Events = make(map[string]shiftSecret)
+	}
+	return store, nil
+}
+
+func (s *shiftSecretStore) loadEncrypted() error {
+	files, err := filepath.Glob(filepath.Join(s.path, "*.enc"))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		if data, err = decryptSecrets(data, s.passphrase); err != nil {
+			return fmt.Errorf("failed to decrypt %s: %w", f, err)
+		}
+		var rec shiftSecret
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", f, err)
+		}
+		if !validEventID(rec.EventID) || filepath.Base(f) != rec.EventID+".enc" {
+			return fmt.Errorf("%s holds the secret for event %q; the file must be named after it", f, rec.EventID)
+		}
+		s.Events[rec.EventID] = rec
+	}
+	return nil
+}
+
+// Save writes the store. Encrypted stores only rewrite the events that were
+// added or changed.
+func (s *shiftSecretStore) Save() error {
+	if s.passphrase != "" {
+		return s.saveEncrypted()
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, append(data, '\n'), 0o600)
+}
+
+func (s *shiftSecretStore) saveEncrypted() error {
+	if err := os.MkdirAll(s.path, 0o755); err != nil {
+		return err
+	}
+	for id := range s.changed {
+		if !validEventID(id) {
+			return fmt.Errorf("invalid Shift2Bikes event id %q", id)
+		}
+		data, err := json.MarshalIndent(s.Events[id], "", "  ")
+		if err != nil {
+			return err
+		}
+		if data, err = encryptSecrets(append(data, '\n'), s.passphrase); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(s.path, id+".enc"), data, 0o600); err != nil {
+			return err
+		}
+	}
+	s.changed = make(map[string]bool)
+	return nil
+}
+
+// Add records a newly created or retrieved event.
+func (s *shiftSecretStore) Add(event *shift2bikesEvent) {
+	rec := shiftSecret{
+		EventID: event.ID,
+		Secret:  event.Secret,
+		Title:   event.Title,
+		Dates:   make(map[string]string),
+		Created: time.Now().Format(time.RFC3339),
+	}
+	if old, ok := s.Events[event.ID]; ok {
+		rec.Created = old.Created
+	}
+	for _, ds := range event.DateStatuses {
+		rec.Dates[ds.Date] = ds.ID
+	}
+	s.Events[event.ID] = rec
+	s.changed[event.ID] = true
+}
+
+// Lookup finds the secret for an event by calendar URL (any of its dates)
+// or by event id.
+func (s *shiftSecretStore) Lookup(urlOrID string) (shiftSecret, bool) {
+	if validEventID(urlOrID) {
+		rec, ok := s.Events[urlOrID]
+		return rec, ok
+	}
+	m := shift2bikesEventRegex.FindStringSubmatch(urlOrID)
+	if m == nil {
+		return shiftSecret{}, false
+	}
+	for _, rec := range s.Events {
+		for _, id := range rec.Dates {
+			if id == m[1] {
+				return rec, true
+			}
+		}
+	}
+	return shiftSecret{}, false
+}
+
+// validEventID reports whether id is a Shift2Bikes event id, all digits, and
+// so safe to name a file in encryptedSecretsDir after.
+func validEventID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, r := range id {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// checkSecretsStorable fails on GitHub Actions without a passphrase: the
+// plain secrets file would be written on a runner that is thrown away.
+func checkSecretsStorable() error {
+	if os.Getenv("GITHUB_ACTIONS") != "" && os.Getenv(secretsPassphraseEnv) == "" {
+		return fmt.Errorf("%s is not set; edit secrets would be lost with the runner", secretsPassphraseEnv)
+	}
+	return nil
+}
+
+// saveShiftSecret stores the secret of an event just returned by the API.
+func saveShiftSecret(event *shift2bikesEvent) error {
+	if event.Secret == "" {
+		return nil
+	}
+	if !validEventID(event.ID) {
+		return fmt.Errorf("Shift2Bikes returned an invalid event id %q", event.ID)
+	}
+	if err := checkSecretsStorable(); err != nil {
+		return err
+	}
+	store, err := loadShiftSecrets()
+	if err != nil {
+		return err
+	}
+	store.Add(event)
+	if err := store.Save(); err != nil {
+		return fmt.Errorf("failed to save %s: %w", store.path, err)
+	}
+	fmt.Printf("Saved edit secret for event %s to %s\n", event.ID, store.path)
+	return nil
+}
+
+// Encrypted files are base64(salt | nonce | AES-256-GCM ciphertext), with
+// the key derived from the passphrase using PBKDF2-SHA256.
+
+func secretsKey(passphrase string, salt []byte) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, passphrase, salt, secretsKDFIterations, 32)
+}
+
+func encryptSecrets(plain []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := secretsCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append(append(salt, nonce...), gcm.Seal(nil, nonce, plain, nil)...)
+	return []byte(base64.StdEncoding.EncodeToString(out) + "\n"), nil
+}
+
+func decryptSecrets(data []byte, passphrase string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < 16 {
+		return nil, errors.New("file is too short")
+	}
+	salt, raw := raw[:16], raw[16:]
+	gcm, err := secretsCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < gcm.NonceSize() {
+		return nil, errors.New("file is too short")
+	}
+	plain, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupted file")
+	}
+	return plain, nil
+}
+
+func secretsCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := secretsKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ShiftSecrets manages stored Shift2Bikes edit secrets.
+type ShiftSecrets mg.Namespace
+
+// List prints the events with stored edit secrets.
+//
+// Usage: mage shiftSecrets:list
+func (ShiftSecrets) List() error {
+	store, err := loadShiftSecrets()
+	if err != nil {
+		return err
+	}
+	if len(store.Events) == 0 {
+		fmt.Printf("No secrets stored in %s\n", store.path)
+		return nil
+	}
+
+	recs := make([]shiftSecret, 0, len(store.Events))
+	for _, rec := range store.Events {
+		recs = append(recs, rec)
+	}
+	sort.Slice(recs, func(i, j int) bool { return recs[i].Created < recs[j].Created })
+
+	fmt.Printf("%d events in %s:\n", len(recs), store.path)
+	for _, rec := range recs {
+		fmt.Printf("\n%s (event %s, secret %s…)\n", rec.Title, rec.EventID, rec.Secret[:min(4, len(rec.Secret))])
+		for _, u := range rec.URLs() {
+			fmt.Printf("  %s\n", u)
+		}
+	}
+	return nil
+}
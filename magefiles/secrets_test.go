@@ -0,0 +1,83 @@
+//go:build mage
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidEventID(t *testing.T) {
+	for id, want := range map[string]bool{
+		"7":           true,
+		"23669":       true,
+		"":            false,
+		"../x":        false,
+		"12/../../34": false,
+		"12.enc":      false,
+		"-1":          false,
+		"١٢":          false, // Arabic-Indic digits
+	} {
+		if got := validEventID(id); got != want {
+			t.Errorf("validEventID(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func testSecretStore(dir string) *shiftSecretStore {
+	return &shiftSecretStore{path: dir, passphrase: "test", changed: make(map[string]bool), Events: make(map[string]shiftSecret)}
+}
+
+func TestEncryptedSecretsRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	store := testSecretStore(dir)
+	store.Add(&shift2bikesEvent{
+		shift2bikesPayload: shift2bikesPayload{ID: "77", Secret: "sek", Title: "Bike Happy Hour",
+			DateStatuses: []dateStatus{{ID: "30000", Date: "2026-11-09"}}},
+	})
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "77.enc")); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := testSecretStore(dir)
+	if err := loaded.loadEncrypted(); err != nil {
+		t.Fatal(err)
+	}
+	for _, sel := range []string{"77", "https://www.shift2bikes.org/calendar/event-30000"} {
+		if rec, ok := loaded.Lookup(sel); !ok || rec.Secret != "sek" {
+			t.Errorf("Lookup(%q) = %+v, %v", sel, rec, ok)
+		}
+	}
+	if _, ok := loaded.Lookup("../77"); ok {
+		t.Error("Lookup(../77) found a secret")
+	}
+
+	// A file named after another event is refused
+	if err := os.Rename(filepath.Join(dir, "77.enc"), filepath.Join(dir, "78.enc")); err != nil {
+		t.Fatal(err)
+	}
+	if err := testSecretStore(dir).loadEncrypted(); err == nil {
+		t.Error("loading 78.enc holding event 77 succeeded, want an error")
+	}
+}
+
+func TestEncryptedSecretsRejectPathIDs(t *testing.T) {
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "secrets")
+	store := testSecretStore(dir)
+	store.Add(&shift2bikesEvent{shift2bikesPayload: shift2bikesPayload{ID: "../x", Secret: "sek"}})
+	if err := store.Save(); err == nil {
+		t.Error("saving event ../x succeeded, want an error")
+	}
+	if _, err := os.Stat(filepath.Join(parent, "x.enc")); !os.IsNotExist(err) {
+		t.Errorf("x.enc was written outside the secrets directory (stat: %v)", err)
+	}
+
+	if err := saveShiftSecret(&shift2bikesEvent{shift2bikesPayload: shift2bikesPayload{ID: "../x", Secret: "sek"}}); err == nil {
+		t.Error("saveShiftSecret with id ../x succeeded, want an error")
+	}
+}
@@ -0,0 +1,326 @@
+//go:build mage
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultShift2BikesBaseURL = "https://www.shift2bikes.org"
+	shift2bikesCalendarURL    = "https://www.shift2bikes.org/calendar/event-"
+	shift2bikesUserAgent      = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+)
+
+// Date status codes used by the Shift2Bikes API.
+const (
+	shiftStatusActive    = "A"
+	shiftStatusCancelled = "C"
+)
+
+// shift2bikesAreas are the area codes the Shift2Bikes API accepts.
+var shift2bikesAreas = []string{"N", "NE", "NW", "SE", "SW", "E", "W"}
+
+// shift2bikesPayload is an event as sent to and returned by manage_event.php.
+// ID and Secret are empty when creating and required when updating.
+type shift2bikesPayload struct {
+	ID            string       `json:"id"`
+	Secret        string       `json:"secret"`
+	Title         string       `json:"title"`
+	Details       string       `json:"details"`
+	Audience      string       `json:"audience"`
+	Time          string       `json:"time"`
+	TimeDetails   string       `json:"timedetails"`
+	EventDuration string       `json:"eventduration"`
+	Area          string       `json:"area"`
+	Venue         string       `json:"venue"`
+	Address       string       `json:"address"`
+	LocDetails    string       `json:"locdetails"`
+	LocEnd        string       `json:"locend"`
+	Length        string       `json:"length"`
+	Organizer     string       `json:"organizer"`
+	Email         string       `json:"email"`
+	HideEmail     string       `json:"hideemail"`
+	WebName       string       `json:"webname"`
+	WebURL        string       `json:"weburl"`
+	Phone         string       `json:"phone"`
+	Contact       string       `json:"contact"`
+	TinyTitle     string       `json:"tinytitle"`
+	PrintDescr    string       `json:"printdescr"`
+	CodeOfConduct string       `json:"code_of_conduct"`
+	ReadComic     string       `json:"read_comic"`
+	DateStatuses  []dateStatus `json:"datestatuses"`
+}
+
+// dateStatus is one date of an event. ID is the per-date id used in
+// calendar URLs (event-<ID>); Status is shiftStatusActive or shiftStatusCancelled.
+type dateStatus struct {
+	ID        string `json:"id"`
+	Date      string `json:"date"`
+	Status    string `json:"status"`
+	Newsflash string `json:"newsflash"`
+}
+
+// shift2bikesEvent is the response from manage_event.php and
+// retrieve_event.php: the full event plus server-side state.
+type shift2bikesEvent struct {
+	shift2bikesPayload
+	Published bool `json:"published"`
+}
+
+// DateID returns the per-date id for date (YYYY-MM-DD), or "".
+func (e *shift2bikesEvent) DateID(date string) string {
+	for _, ds := range e.DateStatuses {
+		if ds.Date == date {
+			return ds.ID
+		}
+	}
+	return ""
+}
+
+// shift2bikesOccurrence is one public calendar entry from events.php.
+type shift2bikesOccurrence struct {
+	ID          string `json:"id"`
+	CaldailyID  string `json:"caldaily_id"`
+	Title       string `json:"title"`
+	Date        string `json:"date"`
+	Time        string `json:"time"`
+	TimeDetails string `json:"timedetails"`
+	Venue       string `json:"venue"`
+	Address     string `json:"address"`
+	LocDetails  string `json:"locdetails"`
+	LocEnd      string `json:"locend"`
+	Area        string `json:"area"`
+	Organizer   string `json:"organizer"`
+	Details     string `json:"details"`
+	Newsflash   string `json:"newsflash"`
+	Cancelled   bool   `json:"cancelled"`
+	Shareable   string `json:"shareable"`
+}
+
+// errShift2BikesNotFound is returned by Fetch when the calendar has no such
+// event. The public API only lists published events, so this also covers
+// events whose confirmation link was never clicked.
+var errShift2BikesNotFound = errors.New("not on the public calendar")
+
+// shift2bikesError is an error reported by the API, with per-field messages
+// when validation failed.
+type shift2bikesError struct {
+	StatusCode int
+	Message    string            `json:"message"`
+	Fields     map[string]string `json:"fields"`
+}
+
+func (e *shift2bikesError) Error() string {
+	msg := fmt.Sprintf("Shift2Bikes API returned HTTP %d", e.StatusCode)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	fields := make([]string, 0, len(e.Fields))
+	for field := range e.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		msg += fmt.Sprintf("; %s: %s", field, e.Fields[field])
+	}
+	return msg
+}
+
+// transientShift2BikesError reports whether a failed API call may succeed if
+// tried again later.
+func transientShift2BikesError(err error) bool {
+	var apiErr *shift2bikesError
+	if errors.As(err, &apiErr) {
+		return retryableStatus(apiErr.StatusCode)
+	}
+	return retryableError(err)
+}
+
+// shift2bikesClient talks to the Shift2Bikes calendar API. BaseURL can point
+// at a stand-in server for testing.
+type shift2bikesClient struct {
+	BaseURL string
+	HTTP    *http.Client
+}
+
+// newShift2BikesClient returns a client for SHIFT2BIKES_BASE_URL, or the
+// public site when it is unset.
+func newShift2BikesClient() *shift2bikesClient {
+	base := os.Getenv("SHIFT2BIKES_BASE_URL")
+	if base == "" {
+		base = defaultShift2BikesBaseURL
+	}
+	return &shift2bikesClient{
+		BaseURL: strings.TrimSuffix(base, "/"),
+		HTTP:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// shift2bikesEventURL returns the public calendar URL for a per-date id.
+func shift2bikesEventURL(dateID string) string {
+	return shift2bikesCalendarURL + dateID
+}
+
+// Create submits a new event. The returned event carries the secret needed to
+// edit it later and the per-date ids.
+func (c *shift2bikesClient) Create(p *shift2bikesPayload) (*shift2bikesEvent, error) {
+	if p.ID != "" || p.Secret != "" {
+		return nil, fmt.Errorf("create: payload already has an id; use Update")
+	}
+	return c.manage(p)
+}
+
+// Update replaces an existing event; p must carry its ID and Secret.
+func (c *shift2bikesClient) Update(p *shift2bikesPayload) (*shift2bikesEvent, error) {
+	if p.ID == "" || p.Secret == "" {
+		return nil, fmt.Errorf("update: payload needs both id and secret")
+	}
+	return c.manage(p)
+}
+
+func (c *shift2bikesClient) manage(p *shift2bikesPayload) (*shift2bikesEvent, error) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	var event shift2bikesEvent
+	if err := c.do("POST", "/api/manage_event.php", nil, body, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// Retrieve fetches the full, editable event by event id and secret.
+func (c *shift2bikesClient) Retrieve(id, secret string) (*shift2bikesEvent, error) {
+	var event shift2bikesEvent
+	query := url.Values{"id": {id}, "secret": {secret}}
+	if err := c.do("GET", "/api/retrieve_event.php", query, nil, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// Fetch returns the public calendar entry for a per-date id (the number in
+// an event-<id> URL).
+func (c *shift2bikesClient) Fetch(dateID string) (*shift2bikesOccurrence, error) {
+	var result struct {
+		Events []shift2bikesOccurrence `json:"events"`
+	}
+	if err := c.do("GET", "/api/events.php", url.Values{"id": {dateID}}, nil, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Events) == 0 {
+		return nil, fmt.Errorf("shift2bikes event %s: %w", dateID, errShift2BikesNotFound)
+	}
+	return &result.Events[0], nil
+}
+
+// shift2bikesMaxRangeDays is the longest date range requested from events.php
+// at once; longer ranges are fetched in pieces.
+const shift2bikesMaxRangeDays = 45
+
+// Range returns the public calendar entries between from and to, inclusive.
+func (c *shift2bikesClient) Range(from, to time.Time) ([]shift2bikesOccurrence, error) {
+	var all []shift2bikesOccurrence
+	for start := from; !start.After(to); start = start.AddDate(0, 0, shift2bikesMaxRangeDays) {
+		end := start.AddDate(0, 0, shift2bikesMaxRangeDays-1)
+		if end.After(to) {
+			end = to
+		}
+		var result struct {
+			Events []shift2bikesOccurrence `json:"events"`
+		}
+		query := url.Values{"startdate": {start.Format("2006-01-02")}, "enddate": {end.Format("2006-01-02")}}
+		if err := c.do("GET", "/api/events.php", query, nil, &result); err != nil {
+			return nil, err
+		}
+		all = append(all, result.Events...)
+	}
+	return all, nil
+}
+
+// CancelDate marks one date of an event as cancelled, with an optional
+// newsflash shown on the calendar.
+func (c *shift2bikesClient) CancelDate(id, secret, date, newsflash string) (*shift2bikesEvent, error) {
+	event, err := c.Retrieve(id, secret)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for i := range event.DateStatuses {
+		if event.DateStatuses[i].Date == date {
+			event.DateStatuses[i].Status = shiftStatusCancelled
+			event.DateStatuses[i].Newsflash = newsflash
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("event %s has no date %s", id, date)
+	}
+	payload := event.shift2bikesPayload
+	payload.ID, payload.Secret = id, secret
+	return c.Update(&payload)
+}
+
+func (c *shift2bikesClient) do(method, path string, query url.Values, body []byte, out any) error {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", shift2bikesUserAgent)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	// Errors come back as {"error": {"message": ..., "fields": {...}}}
+	var apiErr struct {
+		Error *shift2bikesError `json:"error"`
+	}
+	if json.Unmarshal(respBody, &apiErr) == nil && apiErr.Error != nil {
+		apiErr.Error.StatusCode = resp.StatusCode
+		return apiErr.Error
+	}
+	if resp.StatusCode >= 400 {
+		return &shift2bikesError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(respBody))}
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+//go:build mage
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testShift2Bikes starts a stand-in API server and returns a client for it.
+func testShift2Bikes(t *testing.T, handler http.HandlerFunc) *shift2bikesClient {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return &shift2bikesClient{BaseURL: srv.URL, HTTP: srv.Client()}
+}
+
+// echoManage answers manage_event.php by echoing the payload back with an
+// id, a secret and per-date ids, the way the real API does.
+func echoManage(t *testing.T, got *shift2bikesPayload) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/manage_event.php" {
+			t.Errorf("request = %s %s, want POST /api/manage_event.php", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(got); err != nil {
+			t.Fatalf("decoding payload: %v", err)
+		}
+		event := shift2bikesEvent{shift2bikesPayload: *got}
+		if event.ID == "" {
+			event.ID, event.Secret = "77", "sek"
+		}
+		for i := range event.DateStatuses {
+			event.DateStatuses[i].ID = fmt.Sprint(30000 + i)
+		}
+		json.NewEncoder(w).Encode(event)
+	}
+}
+
+func TestShift2BikesCreate(t *testing.T) {
+	var got shift2bikesPayload
+	c := testShift2Bikes(t, echoManage(t, &got))
+
+	event, err := c.Create(&shift2bikesPayload{
+		Title:        "Bike Happy Hour",
+		DateStatuses: []dateStatus{{Date: "2026-11-09", Status: shiftStatusActive}, {Date: "2026-11-23", Status: shiftStatusActive}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != "" || got.Secret != "" {
+		t.Errorf("create sent id %q and secret %q, want neither", got.ID, got.Secret)
+	}
+	if event.ID != "77" || event.Secret != "sek" {
+		t.Errorf("event id, secret = %q, %q; want 77, sek", event.ID, event.Secret)
+	}
+	if id := event.DateID("2026-11-23"); id != "30001" {
+		t.Errorf("DateID(2026-11-23) = %q, want 30001", id)
+	}
+
+	if _, err := c.Create(&shift2bikesPayload{ID: "77"}); err == nil {
+		t.Error("Create with an id succeeded, want an error")
+	}
+}
+
+func TestShift2BikesUpdate(t *testing.T) {
+	var got shift2bikesPayload
+	c := testShift2Bikes(t, echoManage(t, &got))
+
+	if _, err := c.Update(&shift2bikesPayload{ID: "77", Secret: "sek", Title: "Renamed"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != "77" || got.Secret != "sek" || got.Title != "Renamed" {
+		t.Errorf("update sent %+v", got)
+	}
+
+	if _, err := c.Update(&shift2bikesPayload{ID: "77"}); err == nil {
+		t.Error("Update without a secret succeeded, want an error")
+	}
+}
+
+func TestShift2BikesRetrieve(t *testing.T) {
+	c := testShift2Bikes(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/retrieve_event.php" {
+			t.Errorf("path = %s, want /api/retrieve_event.php", r.URL.Path)
+		}
+		if q := r.URL.Query(); q.Get("id") != "77" || q.Get("secret") != "sek" {
+			t.Errorf("query = %s, want id=77 and secret=sek", r.URL.RawQuery)
+		}
+		io.WriteString(w, `{"id":"77","title":"Bike Happy Hour","published":true,
+			"datestatuses":[{"id":"30000","date":"2026-11-09","status":"A"}]}`)
+	})
+
+	event, err := c.Retrieve("77", "sek")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Title != "Bike Happy Hour" || !event.Published || event.DateID("2026-11-09") != "30000" {
+		t.Errorf("Retrieve = %+v", event)
+	}
+}
+
+func TestShift2BikesFetch(t *testing.T) {
+	c := testShift2Bikes(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("id") {
+		case "30000":
+			io.WriteString(w, `{"events":[{"id":"1","caldaily_id":"30000","title":"Bike Happy Hour","date":"2026-11-09","venue":"BGs Food Cartel"}]}`)
+		default:
+			io.WriteString(w, `{"events":[]}`)
+		}
+	})
+
+	occ, err := c.Fetch("30000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if occ.CaldailyID != "30000" || occ.Venue != "BGs Food Cartel" {
+		t.Errorf("Fetch = %+v", occ)
+	}
+
+	if _, err := c.Fetch("99999"); !errors.Is(err, errShift2BikesNotFound) {
+		t.Errorf("Fetch of an unpublished event = %v, want errShift2BikesNotFound", err)
+	}
+}
+
+func TestShift2BikesRange(t *testing.T) {
+	var ranges []string
+	c := testShift2Bikes(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		ranges = append(ranges, q.Get("startdate")+".."+q.Get("enddate"))
+		fmt.Fprintf(w, `{"events":[{"caldaily_id":"%d","date":%q}]}`, len(ranges), q.Get("startdate"))
+	})
+
+	from := time.Date(2026, time.June, 1, 0, 0, 0, 0, time.Local)
+	to := time.Date(2026, time.August, 31, 0, 0, 0, 0, time.Local)
+	occs, err := c.Range(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"2026-06-01..2026-07-15", "2026-07-16..2026-08-29", "2026-08-30..2026-08-31"}
+	if strings.Join(ranges, " ") != strings.Join(want, " ") {
+		t.Errorf("requested ranges %v, want %v", ranges, want)
+	}
+	if len(occs) != len(want) {
+		t.Errorf("Range returned %d events, want %d", len(occs), len(want))
+	}
+}
+
+func TestShift2BikesCancelDate(t *testing.T) {
+	var got shift2bikesPayload
+	manage := echoManage(t, &got)
+	c := testShift2Bikes(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/retrieve_event.php" {
+			io.WriteString(w, `{"id":"77","title":"Bike Happy Hour","datestatuses":[
+				{"id":"30000","date":"2026-11-09","status":"A"},
+				{"id":"30001","date":"2026-11-23","status":"A"}]}`)
+			return
+		}
+		manage(w, r)
+	})
+
+	if _, err := c.CancelDate("77", "sek", "2026-11-23", "Rain"); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != "77" || got.Secret != "sek" {
+		t.Errorf("update sent id %q and secret %q, want 77 and sek", got.ID, got.Secret)
+	}
+	want := []dateStatus{
+		{ID: "30000", Date: "2026-11-09", Status: shiftStatusActive},
+		{ID: "30001", Date: "2026-11-23", Status: shiftStatusCancelled, Newsflash: "Rain"},
+	}
+	if fmt.Sprint(got.DateStatuses) != fmt.Sprint(want) {
+		t.Errorf("datestatuses = %+v, want %+v", got.DateStatuses, want)
+	}
+
+	if _, err := c.CancelDate("77", "sek", "2026-12-07", ""); err == nil {
+		t.Error("CancelDate of a date the event doesn't have succeeded, want an error")
+	}
+}
+
+func TestShift2BikesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    shift2bikesError
+		wantMsg string
+	}{
+		{
+			name:    "validation",
+			status:  http.StatusBadRequest,
+			body:    `{"error":{"message":"There were errors in your fields","fields":{"title":"Title missing"}}}`,
+			want:    shift2bikesError{StatusCode: 400, Message: "There were errors in your fields", Fields: map[string]string{"title": "Title missing"}},
+			wantMsg: "Shift2Bikes API returned HTTP 400: There were errors in your fields; title: Title missing",
+		},
+		{
+			name:    "several fields in order",
+			status:  http.StatusBadRequest,
+			body:    `{"error":{"message":"There were errors in your fields","fields":{"venue":"Venue missing","address":"Address missing","title":"Title missing"}}}`,
+			want:    shift2bikesError{StatusCode: 400, Message: "There were errors in your fields", Fields: map[string]string{"address": "Address missing", "title": "Title missing", "venue": "Venue missing"}},
+			wantMsg: "Shift2Bikes API returned HTTP 400: There were errors in your fields; address: Address missing; title: Title missing; venue: Venue missing",
+		},
+		{
+			name:    "error with 200",
+			status:  http.StatusOK,
+			body:    `{"error":{"message":"Invalid secret"}}`,
+			want:    shift2bikesError{StatusCode: 200, Message: "Invalid secret"},
+			wantMsg: "Shift2Bikes API returned HTTP 200: Invalid secret",
+		},
+		{
+			name:    "plain text",
+			status:  http.StatusBadGateway,
+			body:    "Bad Gateway\n",
+			want:    shift2bikesError{StatusCode: 502, Message: "Bad Gateway"},
+			wantMsg: "Shift2Bikes API returned HTTP 502: Bad Gateway",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testShift2Bikes(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			})
+
+			_, err := c.Retrieve("77", "sek")
+			var apiErr *shift2bikesError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Retrieve error = %v, want a *shift2bikesError", err)
+			}
+			if fmt.Sprint(*apiErr) != fmt.Sprint(tt.want) {
+				t.Errorf("error = %+v, want %+v", *apiErr, tt.want)
+			}
+			if err.Error() != tt.wantMsg {
+				t.Errorf("message = %q, want %q", err.Error(), tt.wantMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+//go:build mage
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// siteIndex is what scanSite found in the built site.
+type siteIndex struct {
+	dir   string
+	site  *url.URL
+	pages map[string]*sitePage // keyed by file path relative to dir, e.g. about/index.html
+}
+
+// sitePage is a built HTML page, the element ids it defines and the links on it.
+type sitePage struct {
+	path  string // site path, e.g. / or /about/
+	ids   map[string]bool
+	links []*pageLink
+}
+
+// pageLink is one URL-valued attribute in a page. aria-controls is recorded
+// as "#id", and each srcset candidate separately.
+type pageLink struct {
+	URL     string // attribute value with entities decoded
+	Page    string // site path of the page
+	Tag     string
+	Class   string // first class of the element, if any
+	Attr    string
+	Line    int
+	Context *linkContext
+}
+
+// linkContext is the event card or article a link belongs to. Title is
+// filled in once the card's title has been read.
+type linkContext struct {
+	Kind  string
+	Title string
+}
+
+func (l *pageLink) String() string {
+	s := fmt.Sprintf("%s line %d, %s %s", l.Page, l.Line, l.element(), l.Attr)
+	if l.Context != nil && l.Context.Title != "" {
+		s += fmt.Sprintf(" (%s %q)", l.Context.Kind, l.Context.Title)
+	}
+	return s
+}
+
+// element is the tag and first class, e.g. a.link-button.
+func (l *pageLink) element() string {
+	if l.Class != "" {
+		return l.Tag + "." + l.Class
+	}
+	return l.Tag
+}
+
+// scanSite tokenizes every HTML page under dir.
+func scanSite(dir string) (*siteIndex, error) {
+	site, err := siteBaseURL()
+	if err != nil {
+		return nil, err
+	}
+	idx := &siteIndex{dir: dir, site: site, pages: make(map[string]*sitePage)}
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".html") {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		rel = filepath.ToSlash(rel)
+		page, err := scanPage("/"+strings.TrimSuffix(rel, "index.html"), content)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		idx.pages[rel] = page
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+func scanPage(path string, content []byte) (*sitePage, error) {
+	page := &sitePage{path: path, ids: make(map[string]bool)}
+	z := html.NewTokenizer(bytes.NewReader(content))
+	line := 1
+	var ctx *linkContext
+	awaitingTitle := false
+
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if errors.Is(z.Err(), io.EOF) {
+				return page, nil
+			}
+			return nil, z.Err()
+		}
+		tokLine := line
+		line += bytes.Count(z.Raw(), []byte("\n"))
+		tok := z.Token()
+
+		switch tt {
+		case html.TextToken:
+			if text := strings.TrimSpace(tok.Data); awaitingTitle && text != "" {
+				if ctx != nil && ctx.Title == "" {
+					ctx.Title = text
+				}
+				awaitingTitle = false
+			}
+
+		case html.EndTagToken:
+			if tok.Data == "section" || tok.Data == "a" && ctx != nil && ctx.Kind == "article" {
+				ctx = nil
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			classes := strings.Fields(attrValue(tok, "class"))
+			switch {
+			case tok.Data == "section":
+				ctx = nil
+			case tok.Data == "div" && slices.Contains(classes, "event-card"):
+				ctx = &linkContext{Kind: "event card"}
+			case tok.Data == "a" && slices.Contains(classes, "link-button"):
+				ctx = &linkContext{Kind: "article"}
+			}
+			if slices.Contains(classes, "link-title") {
+				awaitingTitle = true
+			}
+
+			link := pageLink{Page: path, Tag: tok.Data, Line: tokLine, Context: ctx}
+			if len(classes) > 0 {
+				link.Class = classes[0]
+			}
+			add := func(attr, u string) {
+				l := link
+				l.Attr, l.URL = attr, u
+				page.links = append(page.links, &l)
+			}
+			for _, a := range tok.Attr {
+				val := strings.TrimSpace(a.Val)
+				switch a.Key {
+				case "id":
+					page.ids[val] = true
+				case "name":
+					if tok.Data == "a" {
+						page.ids[val] = true
+					}
+				case "href", "src":
+					add(a.Key, val)
+				case "srcset":
+					for _, candidate := range strings.Split(val, ",") {
+						if f := strings.Fields(candidate); len(f) > 0 {
+							add(a.Key, f[0])
+						}
+					}
+				case "aria-controls":
+					for _, id := range strings.Fields(val) {
+						add(a.Key, "#"+id)
+					}
+				}
+			}
+		}
+	}
+}
+
+func attrValue(tok html.Token, key string) string {
+	for _, a := range tok.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// ExternalLinks returns the absolute http(s) <a href> links to other sites,
+// with every place each one appears.
+func (idx *siteIndex) ExternalLinks() map[string][]*pageLink {
+	links := make(map[string][]*pageLink)
+	for _, page := range idx.sortedPages() {
+		for _, l := range page.links {
+			if l.Tag != "a" || l.Attr != "href" {
+				continue
+			}
+			u, err := url.Parse(l.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == idx.site.Host {
+				continue
+			}
+			links[l.URL] = append(links[l.URL], l)
+		}
+	}
+	return links
+}
+
+func (idx *siteIndex) sortedPages() []*sitePage {
+	keys := make([]string, 0, len(idx.pages))
+	for k := range idx.pages {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pages := make([]*sitePage, len(keys))
+	for i, k := range keys {
+		pages[i] = idx.pages[k]
+	}
+	return pages
+}
+
+// contentIndex holds the lines of content/*.md, to find which front matter
+// entry a URL in the built site came from.
+type contentIndex map[string][]string
+
+func loadContentIndex(dir string) (contentIndex, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.md"))
+	if err != nil {
+		return nil, err
+	}
+	idx := make(contentIndex)
+	for _, f := range files {
+		content, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		idx[filepath.ToSlash(f)] = strings.Split(string(content), "\n")
+	}
+	return idx, nil
+}
+
+// Find returns file:line for each line mentioning one of the URLs, trying
+// them in order and stopping at the first that is found. Root-relative URLs
+// are also looked for without the leading slash, the way relURL sources are
+// written.
+func (c contentIndex) Find(urls ...string) []string {
+	files := make([]string, 0, len(c))
+	for f := range c {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+
+	for _, u := range urls {
+		needles := []string{u}
+		if rel, ok := strings.CutPrefix(u, "/"); ok && rel != "" && !strings.HasPrefix(rel, "/") {
+			needles = append(needles, rel)
+		}
+		var found []string
+		for _, f := range files {
+			for i, line := range c[f] {
+				if strings.HasPrefix(strings.TrimSpace(line), "#") {
+					continue // commented-out examples
+				}
+				for _, n := range needles {
+					if strings.Contains(line, n) {
+						found = append(found, fmt.Sprintf("%s:%d", f, i+1))
+						break
+					}
+				}
+			}
+		}
+		if len(found) > 0 {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+//go:build mage
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// syncDrift is one disagreement between an events.md entry and Shift2Bikes.
+type syncDrift struct {
+	ev     *docEvent
+	field  string
+	local  string
+	remote string
+	fix    func() // updates the events.md side; nil when it cannot be fixed here
+}
+
+// SyncCheck compares every upcoming events.md entry linked to Shift2Bikes
+// with the calendar: date, start time (for series in data/recurring.yaml),
+// venue and address. Cancelled dates and events that are missing from the
+// public calendar (unpublished, or deleted) are flagged too.
+//
+// Environment variables:
+//
+//	SYNC_ALL  1 to include past events
+//	SYNC_FIX  1 to rewrite the events.md side of fixable mismatches
+//	DRY_RUN   1 to print the SYNC_FIX diff instead of writing
+func SyncCheck() error {
+	doc, err := loadEventsDoc(eventsFile)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadRecurringConfig(recurringFile)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	var linked []*docEvent
+	for _, ev := range doc.Events() {
+		if !shift2bikesEventRegex.MatchString(ev.URL) {
+			continue
+		}
+		if d, err := time.ParseInLocation(eventDateLayout, ev.Date, time.Local); err == nil && d.Before(today) && !envTrue("SYNC_ALL") {
+			continue
+		}
+		linked = append(linked, ev)
+	}
+	fmt.Printf("Checking %d Shift2Bikes events against %s...\n", len(linked), eventsFile)
+
+	occurrences, fetchErrs := fetchOccurrences(linked)
+	var drifts []syncDrift
+	for _, ev := range linked {
+		if err := fetchErrs[ev]; err != nil {
+			drifts = append(drifts, syncDrift{ev: ev, field: "status", local: "listed", remote: syncFetchProblem(err)})
+			continue
+		}
+		drifts = append(drifts, compareWithShift2Bikes(cfg, ev, occurrences[ev])...)
+	}
+
+	if len(drifts) == 0 {
+		fmt.Printf("✓ %d events in sync\n", len(linked))
+		return nil
+	}
+	printDrifts(drifts)
+
+	if !envTrue("SYNC_FIX") {
+		return fmt.Errorf("found %d mismatches; run with SYNC_FIX=1 to update %s", len(drifts), eventsFile)
+	}
+	fixed := 0
+	for _, d := range drifts {
+		if d.fix != nil {
+			d.fix()
+			fixed++
+		}
+	}
+	if fixed > 0 {
+		if err := doc.Commit(); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("\nFixed %d of %d mismatches in %s.\n", fixed, len(drifts), eventsFile)
+	if fixed < len(drifts) {
+		return fmt.Errorf("%d mismatches need fixing on Shift2Bikes", len(drifts)-fixed)
+	}
+	return nil
+}
+
+// fetchOccurrences fetches the calendar entry of each event, five at a time.
+func fetchOccurrences(events []*docEvent) (map[*docEvent]*shift2bikesOccurrence, map[*docEvent]error) {
+	client := newShift2BikesClient()
+	results := make(map[*docEvent]*shift2bikesOccurrence)
+	errs := make(map[*docEvent]error)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 5)
+
+	for _, ev := range events {
+		wg.Add(1)
+		go func(ev *docEvent) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			id := shift2bikesEventRegex.FindStringSubmatch(ev.URL)[1]
+			o, err := client.Fetch(id)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[ev] = err
+			} else {
+				results[ev] = o
+			}
+		}(ev)
+	}
+	wg.Wait()
+	return results, errs
+}
+
+// syncFetchProblem describes why an event could not be fetched. The public
+// API only returns published events, so a missing one is usually unpublished.
+func syncFetchProblem(err error) string {
+	if errors.Is(err, errShift2BikesNotFound) {
+		return "not on calendar (unpublished or deleted)"
+	}
+	return err.Error()
+}
+
+func compareWithShift2Bikes(cfg *recurringConfig, ev *docEvent, o *shift2bikesOccurrence) []syncDrift {
+	var drifts []syncDrift
+	add := func(field, local, remote string, fix func()) {
+		drifts = append(drifts, syncDrift{ev: ev, field: field, local: local, remote: remote, fix: fix})
+	}
+
+	if o.Cancelled && ev.Status != eventStatusCancelled {
+		add("status", orNone(ev.Status), "cancelled", func() {
+			ev.Status = eventStatusCancelled
+			ev.Reason = o.Newsflash
+		})
+	}
+
+	local, err := time.ParseInLocation(eventDateLayout, ev.Date, time.Local)
+	remote, rerr := time.ParseInLocation("2006-01-02", o.Date, time.Local)
+	if err == nil && rerr == nil && !local.Equal(remote) {
+		add("date", ev.Date, remote.Format(eventDateLayout), func() {
+			pd := newParsedDate(remote)
+			ev.Date = pd.display
+			if m := titleDateRegex.FindStringSubmatch(ev.Title); m != nil {
+				ev.Title = pd.short + ev.Title[len(m[0])-1:]
+			}
+		})
+	}
+
+	// Start time and venue are only known for series events
+	if series := seriesFor(cfg, ev, local); series != nil {
+		if p, err := series.payload(local); err == nil && p != nil {
+			if p.Time != "" && o.Time != "" && p.Time != o.Time {
+				add("time", p.Time+" ("+series.Name+")", o.Time, nil)
+			}
+			if p.Venue != "" && o.Venue != "" && !strings.EqualFold(p.Venue, o.Venue) {
+				add("venue", p.Venue+" ("+series.Name+")", o.Venue, nil)
+			}
+		}
+	}
+
+	if loc := matchLocation(o.Venue, o.Address); loc != "" && loc != ev.Start {
+		add("start", ev.Start, loc+" ("+o.Venue+")", func() { ev.Start = loc })
+	}
+	if ev.StartAddress != "" && o.Address != "" && normalizeAddress(ev.StartAddress) != normalizeAddress(o.Address) {
+		add("start_address", ev.StartAddress, o.Address, func() { ev.StartAddress = o.Address })
+	}
+	return drifts
+}
+
+// seriesFor returns the recurring series that generated ev, if any.
+func seriesFor(cfg *recurringConfig, ev *docEvent, d time.Time) *recurringSeries {
+	for _, s := range cfg.Series {
+		if entry, err := s.entry(d); err == nil && entry.Title == ev.Title {
+			return s
+		}
+	}
+	return nil
+}
+
+var addressPunct = regexp.MustCompile(`[.,#]`)
+
+// normalizeAddress makes addresses comparable across punctuation, case and
+// spacing differences.
+func normalizeAddress(a string) string {
+	return strings.Join(strings.Fields(strings.ToLower(addressPunct.ReplaceAllString(a, " "))), " ")
+}
+
+func printDrifts(drifts []syncDrift) {
+	sort.SliceStable(drifts, func(i, j int) bool {
+		return drifts[i].ev.lineOf(drifts[i].field) < drifts[j].ev.lineOf(drifts[j].field)
+	})
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\nLINE\tEVENT\tFIELD\tEVENTS.MD\tSHIFT2BIKES\t")
+	for _, d := range drifts {
+		fixable := ""
+		if d.fix == nil {
+			fixable = "(fix on Shift2Bikes)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", d.ev.lineOf(d.field), d.ev.Title, d.field, d.local, d.remote, fixable)
+	}
+	w.Flush()
+}
+
+// envTrue reports whether an environment variable is set to a true value.
+func envTrue(name string) bool {
+	switch strings.ToLower(os.Getenv(name)) {
+	case "1", "y", "yes", "true":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,326 @@
+//go:build mage
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const eventTemplatesFile = "data/event-templates.yaml"
+
+// shift2bikesRequiredFields are rejected by manage_event.php when empty.
+// Custom types prompt for them instead.
+var shift2bikesRequiredFields = []string{"title", "details", "time", "venue", "address", "area"}
+
+// eventTemplates is data/event-templates.yaml.
+type eventTemplates struct {
+	Defaults map[string]any         `yaml:"defaults"`
+	Venues   map[string]*eventVenue `yaml:"venues"`
+	Types    []*eventType           `yaml:"types"`
+}
+
+type eventVenue struct {
+	Name       string `yaml:"name"`
+	Address    string `yaml:"address"`
+	MapAddress string `yaml:"map_address"`
+	Area       string `yaml:"area"`
+	LocDetails string `yaml:"locdetails"`
+	Location   string `yaml:"location"`
+}
+
+// eventType is a kind of event AddEvent can create.
+type eventType struct {
+	Name        string         `yaml:"name"`
+	Label       string         `yaml:"label"`
+	Hidden      bool           `yaml:"hidden"`
+	Custom      bool           `yaml:"custom"`
+	Title       string         `yaml:"title"`
+	Section     string         `yaml:"section"`
+	Weekday     string         `yaml:"weekday"`
+	Venue       string         `yaml:"venue"`
+	Start       string         `yaml:"start"`
+	End         string         `yaml:"end"`
+	Tags        []string       `yaml:"tags"`
+	ShiftMode   string         `yaml:"shift_mode"`
+	PostRide    string         `yaml:"post_ride"`
+	Shift2Bikes map[string]any `yaml:"shift2bikes"`
+
+	weekday  *time.Weekday
+	venue    *eventVenue
+	defaults map[string]any
+	title    *template.Template
+	postRide *eventType
+}
+
+// eventTemplateData is what title and shift2bikes templates can use.
+type eventTemplateData struct {
+	Short   string // 1/12
+	Display string // January 12, 2026
+	API     string // 2026-01-12
+	Series  bool   // the Shift2Bikes event has several dates; the fields above are the first
+}
+
+func newEventTemplateData(d time.Time) eventTemplateData {
+	pd := newParsedDate(d)
+	return eventTemplateData{Short: pd.short, Display: pd.display, API: pd.api}
+}
+
+func loadEventTemplates(path string) (*eventTemplates, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tmpl eventTemplates
+	if err := yaml.Unmarshal(content, &tmpl); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	byName := make(map[string]*eventType)
+	for _, t := range tmpl.Types {
+		if t.Name == "" {
+			return nil, fmt.Errorf("%s: type without a name", path)
+		}
+		if _, dup := byName[t.Name]; dup {
+			return nil, fmt.Errorf("%s: duplicate type %q", path, t.Name)
+		}
+		byName[t.Name] = t
+	}
+	for _, t := range tmpl.Types {
+		if err := t.compile(&tmpl, byName); err != nil {
+			return nil, fmt.Errorf("%s: type %q: %w", path, t.Name, err)
+		}
+	}
+	return &tmpl, nil
+}
+
+func (t *eventType) compile(tmpl *eventTemplates, byName map[string]*eventType) error {
+	if t.Label == "" {
+		t.Label = t.Name
+	}
+	t.defaults = tmpl.Defaults
+	if t.Weekday != "" {
+		wd, err := parseWeekday(t.Weekday)
+		if err != nil {
+			return err
+		}
+		t.weekday = &wd
+	}
+	if t.Venue != "" {
+		t.venue = tmpl.Venues[t.Venue]
+		if t.venue == nil {
+			return fmt.Errorf("unknown venue %q", t.Venue)
+		}
+	} else {
+		t.venue = &eventVenue{}
+	}
+	if t.Start == "" {
+		t.Start = t.venue.Location
+	}
+	if t.End == "" {
+		t.End = t.Start
+	}
+	for _, tag := range t.Tags {
+		if _, err := parseTags(tag); err != nil {
+			return err
+		}
+	}
+	switch t.ShiftMode {
+	case "", "create", "existing", "skip":
+	default:
+		return fmt.Errorf("invalid shift_mode %q; use create, existing, or skip", t.ShiftMode)
+	}
+	if t.PostRide != "" {
+		if t.postRide = byName[t.PostRide]; t.postRide == nil {
+			return fmt.Errorf("post_ride names unknown type %q", t.PostRide)
+		}
+	}
+	if t.Custom {
+		return nil
+	}
+	if fields := t.shift2bikesFields(); fields != nil {
+		for _, k := range shift2bikesRequiredFields {
+			if k == "address" && t.needsAddress() {
+				continue
+			}
+			if v, _ := fields[k].(string); v == "" {
+				return fmt.Errorf("shift2bikes.%s is required (set it on the type or its venue)", k)
+			}
+		}
+		if _, err := parseArea(fields["area"].(string)); err != nil {
+			return fmt.Errorf("shift2bikes.area: %w", err)
+		}
+		if _, err := parseEventTime(fields["time"].(string)); err != nil {
+			return fmt.Errorf("shift2bikes.time: %w", err)
+		}
+	}
+	if t.Title == "" {
+		return fmt.Errorf("missing title")
+	}
+	title, err := template.New(t.Name).Option("missingkey=error").Parse(t.Title)
+	if err != nil {
+		return fmt.Errorf("invalid title template: %w", err)
+	}
+	t.title = title
+	return nil
+}
+
+// Type returns the type with the given name, or nil.
+func (tmpl *eventTemplates) Type(name string) *eventType {
+	for _, t := range tmpl.Types {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// Menu returns the types offered in AddEvent's menu.
+func (tmpl *eventTemplates) Menu() []*eventType {
+	var menu []*eventType
+	for _, t := range tmpl.Types {
+		if !t.Hidden {
+			menu = append(menu, t)
+		}
+	}
+	return menu
+}
+
+// dateWarnings reports dates that are in the past or, for types with a
+// weekday, fall on another day. Moved holiday dates are a legitimate reason
+// for the latter, so neither is an error.
+func (t *eventType) dateWarnings(dates []parsedDate) []string {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	var warnings []string
+	for _, d := range dates {
+		if d.t.Before(today) {
+			warnings = append(warnings, fmt.Sprintf("%s is in the past", d.display))
+		}
+		if t.weekday != nil && d.t.Weekday() != *t.weekday {
+			warnings = append(warnings, fmt.Sprintf("%s is a %s, but %s events are on %ss", d.display, d.t.Weekday(), t.Label, *t.weekday))
+		}
+	}
+	return warnings
+}
+
+// entry renders the events.md entry for one date.
+func (t *eventType) entry(d time.Time) (eventEntry, error) {
+	var title bytes.Buffer
+	if t.title != nil {
+		if err := t.title.Execute(&title, newEventTemplateData(d)); err != nil {
+			return eventEntry{}, fmt.Errorf("type %q: %w", t.Name, err)
+		}
+	}
+	return eventEntry{
+		Title:        title.String(),
+		Date:         d.Format(eventDateLayout),
+		Start:        t.Start,
+		End:          t.End,
+		StartAddress: t.venue.mapAddress(),
+		Tags:         append([]string(nil), t.Tags...),
+	}, nil
+}
+
+// shift2bikesFields merges the defaults, the venue and the type's own
+// Shift2Bikes fields, or returns nil when the type has none.
+func (t *eventType) shift2bikesFields() map[string]any {
+	if t.Shift2Bikes == nil {
+		return nil
+	}
+	fields := make(map[string]any)
+	for k, v := range t.defaults {
+		fields[k] = v
+	}
+	for k, v := range map[string]string{
+		"venue":      t.venue.Name,
+		"address":    t.venue.Address,
+		"area":       t.venue.Area,
+		"locdetails": t.venue.LocDetails,
+	} {
+		if v != "" {
+			fields[k] = v
+		}
+	}
+	for k, v := range t.Shift2Bikes {
+		fields[k] = v
+	}
+	return fields
+}
+
+// payload renders the type's Shift2Bikes event for one or more dates, or
+// returns nil when the type has no Shift2Bikes fields.
+func (t *eventType) payload(dates ...time.Time) (*shift2bikesPayload, error) {
+	fields := t.shift2bikesFields()
+	if fields == nil {
+		return nil, nil
+	}
+	p, err := renderShift2BikesPayload(fields, dates)
+	if err != nil {
+		return nil, fmt.Errorf("type %q: %w", t.Name, err)
+	}
+	return p, nil
+}
+
+// needsAddress reports whether the type's venue has no address yet, so
+// addEvent has to be given one (EVENT_START_ADDRESS).
+func (t *eventType) needsAddress() bool {
+	return t.Venue != "" && t.venue.Address == ""
+}
+
+func (v *eventVenue) mapAddress() string {
+	if v.MapAddress != "" {
+		return v.MapAddress
+	}
+	return v.Address
+}
+
+// renderShift2BikesPayload executes string fields as templates and builds a
+// payload with one active date status per date. Templates see the first date,
+// with Series set when there is more than one.
+func renderShift2BikesPayload(fields map[string]any, dates []time.Time) (*shift2bikesPayload, error) {
+	if len(dates) == 0 {
+		return nil, fmt.Errorf("shift2bikes: no dates")
+	}
+	data := newEventTemplateData(dates[0])
+	data.Series = len(dates) > 1
+
+	rendered := make(map[string]any, len(fields))
+	for k, v := range fields {
+		str, ok := v.(string)
+		if !ok {
+			rendered[k] = v
+			continue
+		}
+		t, err := template.New(k).Option("missingkey=error").Parse(str)
+		if err != nil {
+			return nil, fmt.Errorf("shift2bikes.%s: %w", k, err)
+		}
+		var b bytes.Buffer
+		if err := t.Execute(&b, data); err != nil {
+			return nil, fmt.Errorf("shift2bikes.%s: %w", k, err)
+		}
+		rendered[k] = b.String()
+	}
+
+	// The templates use the API's JSON field names
+	raw, err := json.Marshal(rendered)
+	if err != nil {
+		return nil, err
+	}
+	var payload shift2bikesPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, fmt.Errorf("shift2bikes: %w", err)
+	}
+	payload.DateStatuses = make([]dateStatus, len(dates))
+	for i, d := range dates {
+		payload.DateStatuses[i] = dateStatus{Date: d.Format("2006-01-02"), Status: shiftStatusActive}
+	}
+	return &payload, nil
+}
@@ -0,0 +1,147 @@
+//go:build mage
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// CheckUnpublished reports upcoming Shift2Bikes events linked from events.md
+// that are not on the public calendar yet, usually because nobody clicked the
+// link in the confirmation email. Errors that may clear up on their own, like
+// timeouts and 5xx responses, are re-checked along with pending events.
+//
+// Environment variables:
+//
+//	UNPUBLISHED_URLS      comma-separated URLs to check instead of every upcoming event
+//	UNPUBLISHED_GRACE     how long to keep re-checking pending events before
+//	                      failing, e.g. 30m (default: check once)
+//	UNPUBLISHED_INTERVAL  time between re-checks (default 1m)
+func CheckUnpublished() error {
+	grace, err := envDuration("UNPUBLISHED_GRACE", 0)
+	if err != nil {
+		return err
+	}
+	interval, err := envDuration("UNPUBLISHED_INTERVAL", time.Minute)
+	if err != nil {
+		return err
+	}
+
+	pending, err := unpublishedCandidates()
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		fmt.Println("No upcoming Shift2Bikes events to check.")
+		return nil
+	}
+
+	client := newShift2BikesClient()
+	deadline := time.Now().Add(grace)
+	// unchecked holds the last transient error per URL; those are retried
+	// like unpublished events until the grace period ends
+	var unchecked map[string]error
+	for {
+		fmt.Printf("Checking %d Shift2Bikes events...\n", len(pending))
+		var still []string
+		unchecked = make(map[string]error)
+		for _, u := range pending {
+			id := shift2bikesEventRegex.FindStringSubmatch(u)[1]
+			_, err := client.Fetch(id)
+			switch {
+			case err == nil:
+				fmt.Printf("  ✓ %s\n", u)
+			case errors.Is(err, errShift2BikesNotFound):
+				fmt.Printf("  … %s is not published yet\n", u)
+				still = append(still, u)
+			case transientShift2BikesError(err):
+				fmt.Printf("  ? %s could not be checked: %v\n", u, err)
+				unchecked[u] = err
+				still = append(still, u)
+			default:
+				return err
+			}
+		}
+		pending = still
+		if len(pending) == 0 {
+			fmt.Println("✓ All events are published")
+			return nil
+		}
+		if !time.Now().Add(interval).Before(deadline) {
+			break
+		}
+		fmt.Printf("Waiting %s (grace period ends %s)...\n", interval, deadline.Format("15:04:05"))
+		time.Sleep(interval)
+	}
+
+	var unpublished []string
+	for _, u := range pending {
+		if unchecked[u] == nil {
+			unpublished = append(unpublished, u)
+		}
+	}
+	if len(unpublished) > 0 {
+		fmt.Println("\nStill unpublished — click the confirmation link in the Ride Westside Gmail:")
+		for _, u := range unpublished {
+			fmt.Printf("  %s\n", u)
+		}
+	}
+	if len(unchecked) > 0 {
+		fmt.Println("\nCould not be checked:")
+		for _, u := range pending {
+			if err := unchecked[u]; err != nil {
+				fmt.Printf("  %s: %v\n", u, err)
+			}
+		}
+		return fmt.Errorf("%d Shift2Bikes events are not published and %d could not be checked", len(unpublished), len(unchecked))
+	}
+	return fmt.Errorf("%d Shift2Bikes events are not published", len(unpublished))
+}
+
+// unpublishedCandidates returns UNPUBLISHED_URLS, or the Shift2Bikes URLs of
+// every event in events.md dated today or later.
+func unpublishedCandidates() ([]string, error) {
+	var urls []string
+	if v := os.Getenv("UNPUBLISHED_URLS"); v != "" {
+		for _, u := range strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' || r == '\n' }) {
+			if !shift2bikesEventRegex.MatchString(u) {
+				return nil, fmt.Errorf("UNPUBLISHED_URLS: %q is not a Shift2Bikes event URL", u)
+			}
+			urls = append(urls, u)
+		}
+		return urls, nil
+	}
+
+	doc, err := loadEventsDoc(eventsFile)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	for _, ev := range doc.Events() {
+		if !shift2bikesEventRegex.MatchString(ev.URL) || ev.Status == eventStatusCancelled {
+			continue
+		}
+		if d, err := time.ParseInLocation(eventDateLayout, ev.Date, time.Local); err == nil && d.Before(today) {
+			continue
+		}
+		urls = append(urls, ev.URL)
+	}
+	return urls, nil
+}
+
+func envDuration(name string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q; use e.g. 30m or 1h", name, v)
+	}
+	return d, nil
+}
@@ -0,0 +1,101 @@
+//go:build mage
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testUnpublished points CheckUnpublished at a stand-in API whose answer for
+// each per-date id is the next status in its list: 200 for published, 404
+// for not yet published, anything else as an error. The last status repeats.
+func testUnpublished(t *testing.T, statuses map[string][]int) {
+	t.Helper()
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		id := r.URL.Query().Get("id")
+		list := statuses[id]
+		status := list[0]
+		if len(list) > 1 {
+			statuses[id] = list[1:]
+		}
+		switch status {
+		case http.StatusOK:
+			io.WriteString(w, `{"events":[{"caldaily_id":"`+id+`"}]}`)
+		case http.StatusNotFound:
+			io.WriteString(w, `{"events":[]}`)
+		default:
+			w.WriteHeader(status)
+			io.WriteString(w, `{"error":{"message":"try later"}}`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	var urls []string
+	for id := range statuses {
+		urls = append(urls, shift2bikesEventURL(id))
+	}
+	t.Setenv("SHIFT2BIKES_BASE_URL", srv.URL)
+	t.Setenv("UNPUBLISHED_URLS", strings.Join(urls, ","))
+	t.Setenv("UNPUBLISHED_INTERVAL", "1ms")
+}
+
+func TestCheckUnpublished(t *testing.T) {
+	tests := []struct {
+		name     string
+		grace    string
+		statuses map[string][]int
+		wantErr  string
+	}{
+		{
+			name:     "published",
+			statuses: map[string][]int{"30000": {200}, "30001": {200}},
+		},
+		{
+			name:     "unpublished without a grace period",
+			statuses: map[string][]int{"30000": {200}, "30001": {404, 200}},
+			wantErr:  "1 Shift2Bikes events are not published",
+		},
+		{
+			name:     "published during the grace period",
+			grace:    "1s",
+			statuses: map[string][]int{"30000": {404, 404, 200}},
+		},
+		{
+			name:     "transient errors are re-checked",
+			grace:    "1s",
+			statuses: map[string][]int{"30000": {502, 503, 200}, "30001": {404, 502, 200}},
+		},
+		{
+			name:     "transient errors past the grace period",
+			statuses: map[string][]int{"30000": {502}, "30001": {404}},
+			wantErr:  "1 Shift2Bikes events are not published and 1 could not be checked",
+		},
+		{
+			name:     "other errors fail at once",
+			grace:    "1m",
+			statuses: map[string][]int{"30000": {403}},
+			wantErr:  "HTTP 403",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testUnpublished(t, tt.statuses)
+			t.Setenv("UNPUBLISHED_GRACE", tt.grace)
+			err := CheckUnpublished()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("CheckUnpublished: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("CheckUnpublished error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
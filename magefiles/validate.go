@@ -0,0 +1,219 @@
+//go:build mage
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	beavertonSection = "# Beaverton Bike Happy Hours"
+	tigardSection    = "# Tigard Happy Hours"
+)
+
+// eventStatusCancelled marks an event that is kept in events.md but shown
+// struck through.
+const eventStatusCancelled = "cancelled"
+
+// eventDateLayout is the display format of the date field in events.md.
+const eventDateLayout = "January 2, 2006"
+
+// eventLocations are the start/end values offered by the add-event workflow.
+// Keep in sync with .github/workflows/add-event.yml; ValidateEvents checks.
+var eventLocations = []string{
+	"Beaverton",
+	"Tigard",
+	"Hillsboro",
+	"Aloha",
+	"Cedar Hills",
+	"Cedar Mill",
+	"Tualatin",
+	"Garden Home",
+	"Raleigh Hills",
+	"West Portland",
+	"Forest Grove",
+	"Cornelius",
+	"Quatama",
+	"Ladd's Addition",
+}
+
+// knownEventTags are the tags the front-end filter chips are built from.
+var knownEventTags = []string{
+	"happy-hour",
+	"ride",
+	"challenging",
+	"r2r",
+	"cause",
+	"festival",
+	"not-rws",
+}
+
+// sectionRules map event titles to the section they must be filed under.
+var sectionRules = []struct {
+	matches func(title string) bool
+	section string
+}{
+	{func(t string) bool { return strings.HasSuffix(t, "Tigard Happy Hour") }, tigardSection},
+	{func(t string) bool { return strings.Contains(t, "Bike Happy Hour") }, beavertonSection},
+}
+
+var titleDateRegex = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})\s`)
+
+type eventProblem struct {
+	Line    int
+	Title   string
+	Message string
+}
+
+// ValidateEvents checks content/events.md for malformed or inconsistent
+// entries, and that the add-event workflow offers the current event types
+// and locations
+func ValidateEvents() error {
+	doc, err := loadEventsDoc(eventsFile)
+	if err != nil {
+		return err
+	}
+
+	drift, err := checkAddEventOptions()
+	if err != nil {
+		return err
+	}
+	for _, d := range drift {
+		fmt.Println(d)
+	}
+
+	problems := validateEventsDoc(doc)
+	if len(problems) == 0 && len(drift) == 0 {
+		fmt.Printf("✓ %s: %d events OK\n", eventsFile, len(doc.Events()))
+		return nil
+	}
+
+	for _, p := range problems {
+		fmt.Printf("%s:%d: %s: %s\n", eventsFile, p.Line, p.Title, p.Message)
+	}
+	if len(problems) == 0 {
+		return fmt.Errorf("%s is out of sync with the event types and locations", addEventWorkflow)
+	}
+	return fmt.Errorf("found %d problems in %s", len(problems), eventsFile)
+}
+
+func validateEventsDoc(doc *eventsDoc) []eventProblem {
+	var problems []eventProblem
+	report := func(ev *docEvent, key, format string, args ...any) {
+		problems = append(problems, eventProblem{
+			Line:    ev.lineOf(key),
+			Title:   ev.Title,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	locations := make(map[string]bool)
+	for _, l := range eventLocations {
+		locations[l] = true
+	}
+	tags := make(map[string]bool)
+	for _, t := range knownEventTags {
+		tags[t] = true
+	}
+	seenShift := make(map[string]*docEvent)
+	seenEvent := make(map[string]*docEvent)
+
+	for _, section := range doc.sections {
+		for _, ev := range section.Events {
+			if ev.Title == "" {
+				report(ev, "title", "missing title")
+			}
+
+			// Date format and title prefix
+			if ev.Date == "" {
+				report(ev, "date", "missing date")
+			} else if d, err := time.Parse(eventDateLayout, ev.Date); err != nil {
+				report(ev, "date", "date %q is not in %q format", ev.Date, eventDateLayout)
+			} else if m := titleDateRegex.FindStringSubmatch(ev.Title); m != nil {
+				month, _ := strconv.Atoi(m[1])
+				day, _ := strconv.Atoi(m[2])
+				if time.Month(month) != d.Month() || day != d.Day() {
+					report(ev, "title", "title prefix %s/%s does not match date %q", m[1], m[2], ev.Date)
+				}
+			}
+
+			// Locations
+			if ev.Start == "" {
+				report(ev, "start", "missing start")
+			} else if !locations[ev.Start] {
+				report(ev, "start", "unknown start location %q", ev.Start)
+			}
+			if ev.End != "" && !locations[ev.End] {
+				report(ev, "end", "unknown end location %q", ev.End)
+			}
+
+			// Tags
+			for _, t := range ev.Tags {
+				if !tags[t] {
+					report(ev, "tags", "unknown tag %q (known: %s)", t, strings.Join(knownEventTags, ", "))
+				}
+			}
+
+			// Status
+			switch ev.Status {
+			case "", eventStatusCancelled:
+			default:
+				report(ev, "status", "unknown status %q (only %q is supported)", ev.Status, eventStatusCancelled)
+			}
+			if ev.Reason != "" && ev.Status == "" {
+				report(ev, "reason", "reason set without a status")
+			}
+
+			// Duplicate entries; title and date identify an event elsewhere
+			if ev.Title != "" && ev.Date != "" {
+				key := ev.Title + "\x00" + ev.Date
+				if first, ok := seenEvent[key]; ok {
+					report(ev, "title", "duplicate event (same title and date as line %d)", first.Line)
+				} else {
+					seenEvent[key] = ev
+				}
+			}
+
+			// Duplicate Shift2Bikes links
+			if m := shift2bikesEventRegex.FindStringSubmatch(ev.URL); m != nil {
+				if first, ok := seenShift[m[1]]; ok {
+					report(ev, "url", "duplicate shift2bikes event %s (also used by %q on line %d)", m[1], first.Title, first.lineOf("url"))
+				} else {
+					seenShift[m[1]] = ev
+				}
+			}
+
+			// Section placement
+			for _, rule := range sectionRules {
+				if !rule.matches(ev.Title) {
+					continue
+				}
+				if !strings.Contains(section.Comment, rule.section) {
+					where := "outside any section"
+					if section.Comment != "" {
+						where = fmt.Sprintf("under %q", section.Comment)
+					}
+					report(ev, "title", "filed %s; expected %q", where, rule.section)
+				}
+				break
+			}
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+	return problems
+}
+
+// lineOf returns the line of key in the event, falling back to the event's
+// first line when the key is absent.
+func (ev *docEvent) lineOf(key string) int {
+	if l, ok := ev.FieldLines[key]; ok {
+		return l
+	}
+	return ev.Line
+}
@@ -0,0 +1,76 @@
+//go:build mage
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const addEventWorkflow = ".github/workflows/add-event.yml"
+
+// workflowInput is one workflow_dispatch input of a workflow file.
+type workflowInput struct {
+	Options []string `yaml:"options"`
+}
+
+// workflowInputs reads the workflow_dispatch inputs of a workflow file.
+func workflowInputs(path string) (map[string]workflowInput, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var wf struct {
+		On struct {
+			WorkflowDispatch struct {
+				Inputs map[string]workflowInput `yaml:"inputs"`
+			} `yaml:"workflow_dispatch"`
+		} `yaml:"on"`
+	}
+	if err := yaml.Unmarshal(content, &wf); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return wf.On.WorkflowDispatch.Inputs, nil
+}
+
+// checkAddEventOptions reports choice inputs of the add-event workflow that
+// have drifted from data/event-templates.yaml and eventLocations, since
+// GitHub can't build the options from either.
+func checkAddEventOptions() ([]string, error) {
+	tmpl, err := loadEventTemplates(eventTemplatesFile)
+	if err != nil {
+		return nil, err
+	}
+	inputs, err := workflowInputs(addEventWorkflow)
+	if err != nil {
+		return nil, err
+	}
+
+	var types []string
+	for _, t := range tmpl.Menu() {
+		types = append(types, t.Name)
+	}
+	want := []struct {
+		input   string
+		options []string
+		source  string
+	}{
+		{"event_type", types, "the menu types in " + eventTemplatesFile},
+		{"event_start", eventLocations, "eventLocations"},
+		{"event_end", eventLocations, "eventLocations"},
+	}
+
+	var problems []string
+	for _, w := range want {
+		got := inputs[w.input].Options
+		if !slices.Equal(got, w.options) {
+			problems = append(problems, fmt.Sprintf("%s: %s options [%s] don't match %s [%s]",
+				addEventWorkflow, w.input, strings.Join(got, ", "), w.source, strings.Join(w.options, ", ")))
+		}
+	}
+	return problems, nil
+}